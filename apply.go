@@ -0,0 +1,228 @@
+package goflat
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// setter assigns values to the elements of an object by path.
+type setter struct {
+	o *options
+	// alloc allows to allocate nil pointers and maps, and to grow slices.
+	alloc bool
+	// formatter, if set, parses the strings into times and bytes, see convertFormatted.
+	formatter *ValueFormatter
+	// foldKeys makes key field values match case-insensitively, as they are lower-cased in environment variables.
+	foldKeys bool
+}
+
+// set assigns value to the element at path inside v. v must be settable, or be a non-nil map.
+// key is a key field name, if v is a slice addressed by key field.
+func (s *setter) set(v reflect.Value, path []pathElem, key string, value interface{}) error {
+	if len(path) == 0 {
+		if !v.CanSet() {
+			return ErrUnaddressable
+		}
+		res, err := convertFormatted(value, v.Type(), s.formatter)
+		if err != nil {
+			return err
+		}
+		v.Set(res)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			if !s.alloc || !v.CanSet() {
+				return fmt.Errorf("%w: nil pointer", ErrUnaddressable)
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return s.set(v.Elem(), path, key, value)
+	case reflect.Interface:
+		return s.setInterface(v, path, value)
+	case reflect.Struct:
+		for _, field := range s.o.structFields(v.Type()) {
+			if field.name == path[0].name && !path[0].keyed {
+				return s.set(v.Field(field.index), path[1:], field.key, value)
+			}
+		}
+		return fmt.Errorf("%w: no field %q in %v", ErrNotFound, path[0].name, v.Type())
+	case reflect.Map:
+		return s.setMap(v, path, value)
+	case reflect.Slice, reflect.Array:
+		if key == "" {
+			key = s.o.sliceKeys[v.Type().Elem()]
+		}
+		return s.setSliceOrArray(v, path, key, value)
+	}
+	return fmt.Errorf("%w: can't find %q in %v", ErrNotFound, path[0].name, v.Type())
+}
+
+func (s *setter) setInterface(v reflect.Value, path []pathElem, value interface{}) error {
+	if v.IsNil() {
+		if !s.alloc || !v.CanSet() || v.NumMethod() > 0 {
+			return fmt.Errorf("%w: nil interface", ErrUnaddressable)
+		}
+		v.Set(reflect.ValueOf(make(map[string]interface{})))
+	}
+	elem := v.Elem()
+	if elem.Kind() == reflect.Pointer && !elem.IsNil() {
+		return s.set(elem.Elem(), path, "", value)
+	}
+	if elem.Kind() == reflect.Map {
+		return s.set(elem, path, "", value)
+	}
+	// the value stored in an interface is not addressable, so modify its copy.
+	if !v.CanSet() {
+		return ErrUnaddressable
+	}
+	cp := reflect.New(elem.Type()).Elem()
+	cp.Set(elem)
+	if err := s.set(cp, path, "", value); err != nil {
+		return err
+	}
+	v.Set(cp)
+	return nil
+}
+
+func (s *setter) setMap(v reflect.Value, path []pathElem, value interface{}) error {
+	if path[0].keyed {
+		return fmt.Errorf("%w: %q is not a map key", ErrNotFound, path[0].name)
+	}
+	k, err := convert(path[0].name, v.Type().Key())
+	if err != nil {
+		return err
+	}
+	if v.IsNil() {
+		if !s.alloc || !v.CanSet() {
+			return fmt.Errorf("%w: nil map", ErrUnaddressable)
+		}
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	// map elements are not addressable, so modify a copy and put it back.
+	elem := reflect.New(v.Type().Elem()).Elem()
+	if existing := v.MapIndex(k); existing.IsValid() {
+		elem.Set(existing)
+	}
+	if err := s.set(elem, path[1:], "", value); err != nil {
+		return err
+	}
+	v.SetMapIndex(k, elem)
+	return nil
+}
+
+func (s *setter) setSliceOrArray(v reflect.Value, path []pathElem, key string, value interface{}) error {
+	var idx int
+	var err error
+	switch {
+	case path[0].keyed:
+		idx, err = s.keyIndex(v, path[0].keyField, path[0].keyValue)
+	case key != "" && s.o.keyStyle == KeyStyleSegment && !strings.HasPrefix(path[0].name, IndexPrefix):
+		idx, err = s.keyIndex(v, key, path[0].name)
+	default:
+		var ok bool
+		if idx, ok = s.o.parseIndex(path[0].name, key); !ok {
+			err = fmt.Errorf("%w: bad index %q", ErrNotFound, path[0].name)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if idx >= v.Len() {
+		if !s.alloc || v.Kind() != reflect.Slice || !v.CanSet() {
+			return fmt.Errorf("%w: %d, length %d", ErrIndexOutOfRange, idx, v.Len())
+		}
+		grown := reflect.MakeSlice(v.Type(), idx+1, idx+1)
+		reflect.Copy(grown, v)
+		v.Set(grown)
+	}
+	return s.set(v.Index(idx), path[1:], "", value)
+}
+
+// keyIndex returns an index of the element with the key field equal to value.
+// If there is no such an element and allocation is allowed, a new element is appended.
+func (s *setter) keyIndex(v reflect.Value, key, value string) (int, error) {
+	if idx := s.o.findKeyIndex(v, key, value, s.foldKeys); idx >= 0 {
+		return idx, nil
+	}
+	if !s.alloc || v.Kind() != reflect.Slice || !v.CanSet() {
+		return 0, fmt.Errorf("%w: no element with %s=%s", ErrNotFound, key, value)
+	}
+	elemType := v.Type().Elem()
+	elem := reflect.New(elemType).Elem()
+	target := elem
+	if elemType.Kind() == reflect.Pointer {
+		elem.Set(reflect.New(elemType.Elem()))
+		target = elem.Elem()
+	}
+	field, ok := keyFieldValue(target, key)
+	if !ok {
+		return 0, fmt.Errorf("%w: %v has no key field %s", ErrNotFound, elemType, key)
+	}
+	if err := s.set(field, nil, "", value); err != nil {
+		return 0, err
+	}
+	v.Set(reflect.Append(v, elem))
+	return v.Len() - 1, nil
+}
+
+// Apply sets values in an existing object by flattened paths, like the ones returned by Flatten.
+// dst must be a non-nil pointer or a map. Nil pointers, maps and interface{} values on the way are allocated,
+// slices are grown as needed, elements addressed by a key field are appended, if not found.
+// Values are converted to the target types, e.g. strings are parsed into numbers.
+// The changes are applied in the order of sorted paths. Apply stops at the first error,
+// which is returned as *PathError or *ConversionError.
+// Options, that affect naming, like WithDelimeter or ExpandUnexported, must match those used for flattening.
+// Note, that unexported fields can't be set.
+func Apply(dst interface{}, changes map[string]interface{}, opts ...Option) error {
+	o := makeOptions(opts...)
+	root, err := settableRoot(dst)
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(changes))
+	for path := range changes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	s := &setter{o: o, alloc: true}
+	for _, path := range paths {
+		if err := s.set(root, parsePath(path, o.delimeter), "", changes[path]); err != nil {
+			return pathError(path, err)
+		}
+	}
+	return nil
+}
+
+// settableRoot returns a value to start setting from.
+func settableRoot(dst interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(dst)
+	switch {
+	case v.Kind() == reflect.Pointer && !v.IsNil():
+		return v.Elem(), nil
+	case v.Kind() == reflect.Map && !v.IsNil():
+		return v, nil
+	}
+	return reflect.Value{}, fmt.Errorf("%w: %T is not a non-nil pointer or map", ErrUnaddressable, dst)
+}
+
+func pathError(path string, err error) error {
+	var convErr *ConversionError
+	if errors.As(err, &convErr) {
+		convErr.Path = path
+		return convErr
+	}
+	return &PathError{Path: path, Err: err}
+}
+
+// UnflattenAs builds a new value of type T from flattened paths, as Apply does.
+// T may be a struct, a map, a pointer to a struct, etc. Nil pointers inside T are allocated as needed.
+func UnflattenAs[T any](flat map[string]interface{}, opts ...Option) (T, error) {
+	var res T
+	err := Apply(&res, flat, opts...)
+	return res, err
+}
@@ -0,0 +1,150 @@
+package goflat
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/avdva/goflat/testpkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type applyItem struct {
+	ID   int
+	Name string
+}
+
+type applyStruct struct {
+	Int     int
+	Float   float32
+	Str     string `goflat:"str"`
+	Ptr     *int
+	PtrPtr  **applyStruct
+	M       map[string]int
+	MS      map[string]applyItem
+	Any     interface{}
+	Slice   []float64
+	Array   [2]uint8
+	Items   []applyItem  `goflat:",key=ID"`
+	Ptrs    []*applyItem `goflat:",key=Name"`
+	private int
+}
+
+func TestApply(t *testing.T) {
+	a := assert.New(t)
+	var obj applyStruct
+	err := Apply(&obj, map[string]interface{}{
+		"Int":                  "5",
+		"Float":                2,
+		"str":                  123,
+		"Ptr":                  uint8(7),
+		"PtrPtr.Int":           1,
+		"M.k":                  "5",
+		"MS.a.Name":            "name",
+		"MS.a.ID":              1.0,
+		"Any.x.y":              true,
+		"Slice.2":              2.5,
+		"Array.1":              "255",
+		"Items[ID=42].Name":    "item",
+		"Ptrs[Name=first].ID":  "1",
+		"Ptrs[Name=second].ID": 2,
+	})
+	a.NoError(err)
+	seven := 7
+	pp := &applyStruct{Int: 1}
+	a.Equal(applyStruct{
+		Int:    5,
+		Float:  2,
+		Str:    "123",
+		Ptr:    &seven,
+		PtrPtr: &pp,
+		M:      map[string]int{"k": 5},
+		MS:     map[string]applyItem{"a": {ID: 1, Name: "name"}},
+		Any:    map[string]interface{}{"x": map[string]interface{}{"y": true}},
+		Slice:  []float64{0, 0, 2.5},
+		Array:  [2]uint8{0, 255},
+		Items:  []applyItem{{ID: 42, Name: "item"}},
+		Ptrs:   []*applyItem{{ID: 1, Name: "first"}, {ID: 2, Name: "second"}},
+	}, obj)
+
+	a.NoError(Apply(&obj, map[string]interface{}{
+		"Items[ID=42].Name": "renamed",
+		"Slice.0":           1,
+		"M.k2":              2,
+	}))
+	a.Equal([]applyItem{{ID: 42, Name: "renamed"}}, obj.Items)
+	a.Equal([]float64{1, 0, 2.5}, obj.Slice)
+	a.Equal(map[string]int{"k": 5, "k2": 2}, obj.M)
+}
+
+func TestApplyRoundTrip(t *testing.T) {
+	a := assert.New(t)
+	src := testpkg.NewPointerTestStruct()
+	var dst testpkg.PointerTestStruct
+	a.NoError(Apply(&dst, Flatten(src, WithPointerFllowPolicy(PointerPolicyJustValue))))
+	a.Equal(*src.IntPtr, *dst.IntPtr)
+	a.Equal(**src.IntPtrPtr, **dst.IntPtrPtr)
+	a.Equal(*src.ExportedStruct.Data, *dst.ExportedStruct.Data)
+
+	m := map[string]interface{}{}
+	a.NoError(Apply(m, map[string]interface{}{"a/b": 1, "c": "d"}, WithDelimeter("/")))
+	a.Equal(map[string]interface{}{"a": map[string]interface{}{"b": 1}, "c": "d"}, m)
+
+	var obj applyStruct
+	a.NoError(Apply(&obj, map[string]interface{}{"Ptrs.x.ID": 3}, WithKeyStyle(KeyStyleSegment)))
+	a.Equal([]*applyItem{{ID: 3, Name: "x"}}, obj.Ptrs)
+	items := []applyItem{{ID: 1}}
+	a.NoError(Apply(&items, map[string]interface{}{"[ID=1].Name": "a"}, SliceKeyField(reflect.TypeOf(applyItem{}), "ID")))
+	a.Equal([]applyItem{{ID: 1, Name: "a"}}, items)
+}
+
+func TestApplyErrors(t *testing.T) {
+	a := assert.New(t)
+	var obj applyStruct
+	tests := []struct {
+		changes map[string]interface{}
+		opts    []Option
+		err     error
+	}{
+		{changes: map[string]interface{}{"None": 1}, err: ErrNotFound},
+		{changes: map[string]interface{}{"Int.X": 1}, err: ErrNotFound},
+		{changes: map[string]interface{}{"Int": "x"}, err: ErrConversion},
+		{changes: map[string]interface{}{"Array.2": 1}, err: ErrIndexOutOfRange},
+		{changes: map[string]interface{}{"Slice.x": 1}, err: ErrNotFound},
+		{changes: map[string]interface{}{"private": 1}, opts: []Option{ExpandUnexported(true)}, err: ErrUnaddressable},
+		{changes: map[string]interface{}{"private": 1}, err: ErrNotFound},
+	}
+	for _, test := range tests {
+		err := Apply(&obj, test.changes, test.opts...)
+		a.True(errors.Is(err, test.err), "%v", err)
+	}
+	a.True(errors.Is(Apply(obj, nil), ErrUnaddressable))
+	var pathErr *PathError
+	a.True(errors.As(Apply(&obj, map[string]interface{}{"Array.5": 1}), &pathErr))
+	a.Equal("Array.5", pathErr.Path)
+	var convErr *ConversionError
+	a.True(errors.As(Apply(&obj, map[string]interface{}{"M.k": "x"}), &convErr))
+	a.Equal("M.k", convErr.Path)
+}
+
+func TestUnflattenAs(t *testing.T) {
+	a := assert.New(t)
+	type roundTrip struct {
+		Int   int
+		Slice []float64
+		Items []applyItem
+	}
+	src := roundTrip{Int: 1, Slice: []float64{1, 2}, Items: []applyItem{{ID: 1, Name: "a"}}}
+	obj, err := UnflattenAs[roundTrip](Flatten(src))
+	a.NoError(err)
+	a.Equal(src, obj)
+	ptr, err := UnflattenAs[*applyItem](map[string]interface{}{"ID": "2", "Name": "b"})
+	a.NoError(err)
+	a.Equal(&applyItem{ID: 2, Name: "b"}, ptr)
+	m, err := UnflattenAs[map[string]int](map[string]interface{}{"a": 1, "b": "2"})
+	a.NoError(err)
+	a.Equal(map[string]int{"a": 1, "b": 2}, m)
+	_, err = UnflattenAs[applyStruct](map[string]interface{}{"Int": "x"})
+	a.True(errors.Is(err, ErrConversion), "%v", err)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/avdva/goflat/internal/source"
+	"github.com/avdva/goflat/internal/tag"
+)
+
+const (
+	annotation = "//goflat:generate"
+	importPath = "github.com/avdva/goflat"
+)
+
+// generate returns the source of the generated file for the package in dir.
+// The output file is excluded from parsing, so that stale generated code doesn't affect the result.
+func generate(dir, output string) ([]byte, error) {
+	pkg, err := source.Load(dir, func(name string) bool {
+		return name == output
+	})
+	if err != nil {
+		return nil, err
+	}
+	var specs []*ast.TypeSpec
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if annotated(ts.Doc) || (len(gd.Specs) == 1 && annotated(gd.Doc)) {
+					specs = append(specs, ts)
+				}
+			}
+		}
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("no structs with " + annotation + " comment found")
+	}
+	g := &typeGen{pkg: pkg.Types, generated: make(map[*types.TypeName]bool)}
+	for _, ts := range specs {
+		if obj, ok := pkg.Types.Scope().Lookup(ts.Name.Name).(*types.TypeName); ok && ts.TypeParams == nil {
+			g.generated[obj] = true
+		}
+	}
+	for _, ts := range specs {
+		if err := g.generateType(ts); err != nil {
+			return nil, fmt.Errorf("%s: %w", pkg.Fset.Position(ts.Pos()), err)
+		}
+	}
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by goflat-gen. DO NOT EDIT.\n\npackage %s\n\n", pkg.Name)
+	if g.strconv {
+		fmt.Fprintf(&src, "import (\n\t\"strconv\"\n\n\t%q\n)\n", importPath)
+	} else {
+		fmt.Fprintf(&src, "import %q\n", importPath)
+	}
+	src.Write(g.buf.Bytes())
+	return format.Source(src.Bytes())
+}
+
+func annotated(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == annotation {
+			return true
+		}
+	}
+	return false
+}
+
+// typeGen writes the methods of the annotated types.
+type typeGen struct {
+	buf bytes.Buffer
+	pkg *types.Package
+	// generated are the annotated types, whose methods the generated code calls directly.
+	generated map[*types.TypeName]bool
+	// strconv is set, if the generated code uses strconv package.
+	strconv bool
+}
+
+// generateType writes WalkGoflat and GoflatGenerated methods for the type.
+// The fields, which are visited with reflection unless goflat.Emitter.Direct returns true,
+// are visited by the generated code otherwise.
+func (g *typeGen) generateType(ts *ast.TypeSpec) error {
+	name := ts.Name.Name
+	if ts.TypeParams != nil {
+		return fmt.Errorf("%s: generic types are not supported", name)
+	}
+	obj := g.pkg.Scope().Lookup(name)
+	if obj == nil {
+		return fmt.Errorf("%s: type not found", name)
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("%s: not a struct", name)
+	}
+	var body bytes.Buffer
+	var direct, parent bool
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		ft := tag.Parse(field.Name(), reflect.StructTag(st.Tag(i)))
+		if ft.Skip {
+			continue
+		}
+		key := ""
+		if source.IsContainer(field.Type()) {
+			key = ft.Key
+		}
+		switch {
+		case field.Name() != "_" && isBasic(field.Type()) && field.Exported():
+			fmt.Fprintf(&body, "if !e.Emit(%q, v.%s) {\nreturn false\n}\n", ft.Name, field.Name())
+		case field.Name() != "_" && isBasic(field.Type()):
+			fmt.Fprintf(&body, "if e.ExpandUnexported() && !e.Emit(%q, v.%s) {\nreturn false\n}\n", ft.Name, field.Name())
+		case field.Exported() && key == "" && g.direct(field.Type()):
+			direct = true
+			fmt.Fprintf(&body, "if !direct {\nif !e.Walk(%q, \"\", v.%s) {\nreturn false\n}\n} else {\n", ft.Name, field.Name())
+			g.visit(&body, "v."+field.Name(), strconv.Quote(ft.Name), field.Type(), 0)
+			body.WriteString("}\n")
+		case field.Exported():
+			fmt.Fprintf(&body, "if !e.Walk(%q, %q, v.%s) {\nreturn false\n}\n", ft.Name, key, field.Name())
+		default:
+			// the struct is converted to interface{} once for all the fields.
+			parent = true
+			fmt.Fprintf(&body, "if e.ExpandUnexported() {\nif parent == nil {\nparent = v\n}\n"+
+				"if !e.WalkField(%q, %q, parent, %d) {\nreturn false\n}\n}\n", ft.Name, key, i)
+		}
+	}
+	buf := &g.buf
+	fmt.Fprintf(buf, "\n// GoflatGenerated marks %s as having its own WalkGoflat method, see goflat.GeneratedMarker.\n", name)
+	fmt.Fprintf(buf, "func (%s) GoflatGenerated(*%s) {}\n", name, name)
+	fmt.Fprintf(buf, "\n// WalkGoflat visits the fields of %s for goflat.Walk.\n", name)
+	fmt.Fprintf(buf, "func (v %s) WalkGoflat(e goflat.Emitter) bool {\n", name)
+	if direct {
+		buf.WriteString("direct := e.Direct()\n")
+	}
+	if parent {
+		buf.WriteString("var parent interface{}\n")
+	}
+	buf.Write(body.Bytes())
+	buf.WriteString("return true\n}\n")
+	return nil
+}
+
+// direct returns true for the types, which the generated code visits without reflection:
+// basic types, byte slices, annotated structs and pointers to them, and slices, arrays and string-keyed maps
+// of such types.
+func (g *typeGen) direct(typ types.Type) bool {
+	if isBasic(typ) || source.IsBytes(typ) || g.isGenerated(typ) {
+		return true
+	}
+	if ptr, ok := typ.(*types.Pointer); ok {
+		return g.isGenerated(ptr.Elem())
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Slice:
+		return g.direct(t.Elem())
+	case *types.Array:
+		return g.direct(t.Elem())
+	case *types.Map:
+		key, ok := t.Key().Underlying().(*types.Basic)
+		return ok && key.Kind() == types.String && g.direct(t.Elem())
+	}
+	return false
+}
+
+func (g *typeGen) isGenerated(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	return ok && g.generated[named.Obj()]
+}
+
+// visit writes the code, which visits expr of a direct type the same way as goflat.Walk does.
+// name is an expression for its path element, depth is the nesting level of the loops.
+func (g *typeGen) visit(buf *bytes.Buffer, expr, name string, typ types.Type, depth int) {
+	switch {
+	case isBasic(typ):
+		fmt.Fprintf(buf, "if !e.Emit(%s, %s) {\nreturn false\n}\n", name, expr)
+		return
+	case source.IsBytes(typ):
+		if _, ok := typ.Underlying().(*types.Slice); ok {
+			fmt.Fprintf(buf, "if %s == nil {\nif !e.EmitNil(%s) {\nreturn false\n}\n} else ", expr, name)
+		}
+		fmt.Fprintf(buf, "if !e.Emit(%s, %s) {\nreturn false\n}\n", name, expr)
+		return
+	case g.isGenerated(typ):
+		fmt.Fprintf(buf, "e.Enter(%s, nil)\nif !%s.WalkGoflat(e) {\nreturn false\n}\ne.Leave(nil)\n", name, expr)
+		return
+	}
+	if _, ok := typ.(*types.Pointer); ok {
+		// nil pointers are reported as is, others are skipped, if they are already being visited.
+		fmt.Fprintf(buf, "if %[1]s == nil {\nif !e.Emit(%[2]s, %[1]s) {\nreturn false\n}\n} else if e.Enter(%[2]s, %[1]s) {\n"+
+			"if !%[1]s.WalkGoflat(e) {\nreturn false\n}\ne.Leave(%[1]s)\n}\n", expr, name)
+		return
+	}
+	i := fmt.Sprintf("i%d", depth)
+	switch t := typ.Underlying().(type) {
+	case *types.Slice:
+		g.strconv = true
+		// a slice is identified by its first element, as goflat.Walk does.
+		fmt.Fprintf(buf, "if %[1]s == nil {\nif !e.EmitNil(%[2]s) {\nreturn false\n}\n} else if len(%[1]s) > 0 && e.Enter(%[2]s, &%[1]s[0]) {\n"+
+			"for %[3]s := range %[1]s {\n", expr, name, i)
+		g.visit(buf, expr+"["+i+"]", "strconv.Itoa("+i+")", t.Elem(), depth+1)
+		fmt.Fprintf(buf, "}\ne.Leave(&%s[0])\n}\n", expr)
+	case *types.Array:
+		g.strconv = true
+		fmt.Fprintf(buf, "e.Enter(%s, nil)\nfor %s := range %s {\n", name, i, expr)
+		g.visit(buf, expr+"["+i+"]", "strconv.Itoa("+i+")", t.Elem(), depth+1)
+		buf.WriteString("}\ne.Leave(nil)\n")
+	case *types.Map:
+		k, x := fmt.Sprintf("k%d", depth), fmt.Sprintf("x%d", depth)
+		key := k
+		if t.Key() != types.Typ[types.String] {
+			key = "string(" + k + ")"
+		}
+		fmt.Fprintf(buf, "if %[1]s == nil {\nif !e.EmitNil(%[2]s) {\nreturn false\n}\n} else if e.Enter(%[2]s, %[1]s) {\n"+
+			"for %[3]s, %[4]s := range %[1]s {\n", expr, name, k, x)
+		g.visit(buf, x, key, t.Elem(), depth+1)
+		fmt.Fprintf(buf, "}\ne.Leave(%s)\n}\n", expr)
+	}
+}
+
+// isBasic returns true for the unnamed types, which goflat.Walk reports as is.
+func isBasic(typ types.Type) bool {
+	basic, ok := typ.(*types.Basic)
+	if !ok {
+		return false
+	}
+	switch basic.Kind() {
+	case types.Bool, types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint8, types.Uint16, types.Uint32, types.Uint64,
+		types.Float32, types.Float64, types.Complex64, types.Complex128, types.String:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateGolden(t *testing.T) {
+	a := assert.New(t)
+	dir := filepath.Join("..", "..", "testpkg", "gentest")
+	golden, err := os.ReadFile(filepath.Join(dir, "goflat_gen.go"))
+	a.NoError(err)
+	src, err := generate(dir, "goflat_gen.go")
+	a.NoError(err)
+	a.Equal(string(golden), string(src), "run go generate ./testpkg/gentest to update the golden file")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	a := assert.New(t)
+	tests := map[string]string{
+		"none":    "package p\n\ntype T struct{ A int }\n",
+		"generic": "package p\n\n//goflat:generate\ntype T[X any] struct{ A X }\n",
+		"struct":  "package p\n\n//goflat:generate\ntype T int\n",
+	}
+	for name, src := range tests {
+		dir := t.TempDir()
+		a.NoError(os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644))
+		_, err := generate(dir, "goflat_gen.go")
+		a.Error(err, name)
+	}
+}
@@ -0,0 +1,41 @@
+// Command goflat-gen generates WalkGoflat methods for structs, so that goflat.Walk
+// visits their fields without reflection, producing exactly the same paths.
+//
+// Structs are selected with a //goflat:generate comment:
+//
+//	//goflat:generate
+//	type Config struct {
+//		Name  string `goflat:"name"`
+//		Items []Item `goflat:",key=ID"`
+//	}
+//
+// Usage:
+//
+//	goflat-gen [-dir package-dir] [-output goflat_gen.go]
+//
+// or with go generate:
+//
+//	//go:generate go run github.com/avdva/goflat/cmd/goflat-gen
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "package directory")
+	output := flag.String("output", "goflat_gen.go", "output file name in the package directory")
+	flag.Parse()
+	src, err := generate(*dir, *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "goflat-gen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, *output), src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "goflat-gen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/types"
+	"html"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/avdva/goflat/internal/source"
+	"github.com/avdva/goflat/internal/tag"
+)
+
+// runDoc runs the doc command.
+func runDoc(args []string, out io.Writer) error {
+	fl := flag.NewFlagSet("doc", flag.ExitOnError)
+	dir := fl.String("dir", ".", "package directory")
+	format := fl.String("format", "markdown", "output format: markdown or html")
+	defaultTag := fl.String("default-tag", "default", "struct tag with default values")
+	delim := fl.String("delimiter", ".", "key delimiter")
+	stringKeys := fl.Bool("strings", false, "document the keys of goflat.FlattenStrings, where time.Time values are not expanded")
+	fl.Parse(args)
+	if fl.NArg() != 1 {
+		return errors.New("doc: expected one type name")
+	}
+	ref, err := loadReference(*dir, fl.Arg(0), *defaultTag, *delim, *stringKeys)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "markdown":
+		return ref.writeMarkdown(out)
+	case "html":
+		return ref.writeHTML(out)
+	}
+	return fmt.Errorf("doc: unknown format %q", *format)
+}
+
+// reference is a documentation of the keys of a type.
+type reference struct {
+	name string
+	doc  string
+	rows []docRow
+}
+
+// docRow describes a flattened key.
+type docRow struct {
+	key      string
+	typ      string
+	defValue string
+	doc      string
+}
+
+// loadReference loads the package in dir and builds a reference for the named type.
+// If stringKeys is set, time.Time values are documented as single keys, as goflat.FlattenStrings reports them.
+func loadReference(dir, name, defaultTag, delim string, stringKeys bool) (*reference, error) {
+	pkg, err := source.Load(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	docPkg, err := doc.NewFromFiles(pkg.Fset, pkg.Files, pkg.Name)
+	if err != nil {
+		return nil, err
+	}
+	var docType *doc.Type
+	for _, t := range docPkg.Types {
+		if t.Name == name {
+			docType = t
+		}
+	}
+	if docType == nil {
+		return nil, fmt.Errorf("%s: type not found", name)
+	}
+	obj, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s: not a type", name)
+	}
+	if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s: generic types are not supported", name)
+	}
+	dw := &docWalker{
+		pkg:        pkg.Types,
+		docs:       fieldDocs(pkg.Files, pkg.Info),
+		defaultTag: defaultTag,
+		delim:      delim,
+		timeLeaf:   stringKeys,
+		expanding:  make(map[types.Type]string),
+	}
+	dw.visit(obj.Type(), nil)
+	return &reference{name: name, doc: docType.Doc, rows: dw.rows}, nil
+}
+
+// fieldDocs returns the doc comments of struct fields declared in the files.
+func fieldDocs(files []*ast.File, info *types.Info) map[*types.Var]string {
+	docs := make(map[*types.Var]string)
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			st, ok := n.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				text := field.Doc.Text()
+				if text == "" {
+					text = field.Comment.Text()
+				}
+				if text == "" {
+					continue
+				}
+				idents := field.Names
+				if len(idents) == 0 {
+					idents = []*ast.Ident{embeddedIdent(field.Type)}
+				}
+				for _, ident := range idents {
+					if v, ok := info.Defs[ident].(*types.Var); ok {
+						docs[v] = strings.Join(strings.Fields(text), " ")
+					}
+				}
+			}
+			return true
+		})
+	}
+	return docs
+}
+
+// embeddedIdent returns the identifier of an embedded field type, like T, *T or pkg.T.
+func embeddedIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			return e.Sel
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e
+		default:
+			return nil
+		}
+	}
+}
+
+// docWalker visits the keys of a type as goflat.Schema does with the default options.
+type docWalker struct {
+	pkg        *types.Package
+	docs       map[*types.Var]string
+	defaultTag string
+	delim      string
+	// timeLeaf makes time.Time a single key, as the default goflat.ValueFormatter does.
+	timeLeaf bool
+	// expanding are the container types on the current path with their keys.
+	expanding map[types.Type]string
+	// field and tag are the innermost struct field on the current path and its tag.
+	field *types.Var
+	tag   reflect.StructTag
+	rows  []docRow
+}
+
+func (dw *docWalker) visit(typ types.Type, path []string) {
+	if (dw.timeLeaf && isTime(typ)) || source.IsBytes(typ) || isScalar(typ) {
+		dw.add(path, typ, "")
+		return
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Interface:
+		dw.add(path, typ, "")
+	case *types.Pointer:
+		elem := t.Elem()
+		for {
+			p, ok := elem.Underlying().(*types.Pointer)
+			if !ok {
+				break
+			}
+			elem = p.Elem()
+		}
+		if isPrimitive(elem) {
+			dw.add(path, typ, "")
+			return
+		}
+		dw.visit(elem, path)
+	case *types.Struct:
+		if !dw.enter(typ, path) {
+			return
+		}
+		defer delete(dw.expanding, typ)
+		prevField, prevTag := dw.field, dw.tag
+		defer func() {
+			dw.field, dw.tag = prevField, prevTag
+		}()
+		for i := 0; i < t.NumFields(); i++ {
+			field := t.Field(i)
+			if !field.Exported() {
+				continue
+			}
+			ft := tag.Parse(field.Name(), reflect.StructTag(t.Tag(i)))
+			if ft.Skip {
+				continue
+			}
+			dw.field, dw.tag = field, reflect.StructTag(t.Tag(i))
+			if ft.Key != "" && source.IsContainer(field.Type()) {
+				dw.visitSlice(field.Type(), append(path, ft.Name), ft.Key)
+			} else {
+				dw.visit(field.Type(), append(path, ft.Name))
+			}
+		}
+	case *types.Map:
+		if basic, ok := t.Key().Underlying().(*types.Basic); !ok || basic.Kind() != types.String {
+			return
+		}
+		if !dw.enter(typ, path) {
+			return
+		}
+		defer delete(dw.expanding, typ)
+		dw.visit(t.Elem(), append(path, "*"))
+	case *types.Slice, *types.Array:
+		dw.visitSlice(typ, path, "")
+	}
+}
+
+// visitSlice visits the elements of a slice or an array. key is a key field name, set by `goflat:",key=Field"` tag.
+func (dw *docWalker) visitSlice(typ types.Type, path []string, key string) {
+	if !dw.enter(typ, path) {
+		return
+	}
+	defer delete(dw.expanding, typ)
+	elem := source.ListElem(typ)
+	if key != "" && hasField(elem, key) {
+		segment := "[" + key + "=*]"
+		if len(path) > 0 {
+			segment = path[len(path)-1] + segment
+			path = path[:len(path)-1]
+		}
+		dw.visit(elem, append(path, segment))
+		return
+	}
+	dw.visit(elem, append(path, "*"))
+}
+
+// enter marks a container type as being expanded. If the type is already being expanded,
+// it adds a row, which refers to the key, where the type is expanded, and returns false.
+func (dw *docWalker) enter(typ types.Type, path []string) bool {
+	if ref, found := dw.expanding[typ]; found {
+		if ref == "" {
+			dw.add(path, typ, "Same as the root object.")
+		} else {
+			dw.add(path, typ, fmt.Sprintf("Same as `%s`.", ref))
+		}
+		return false
+	}
+	dw.expanding[typ] = strings.Join(path, dw.delim)
+	return true
+}
+
+// add adds a row for a key. note is appended to the doc comment of the innermost struct field.
+func (dw *docWalker) add(path []string, typ types.Type, note string) {
+	row := docRow{
+		key: strings.Join(path, dw.delim),
+		typ: types.TypeString(typ, types.RelativeTo(dw.pkg)),
+	}
+	if dw.field != nil {
+		row.doc = dw.docs[dw.field]
+		row.defValue = dw.tag.Get(dw.defaultTag)
+	}
+	if note != "" {
+		row.doc = strings.TrimSpace(row.doc + " " + note)
+	}
+	dw.rows = append(dw.rows, row)
+}
+
+// isScalar returns true for the types, which goflat.Walk reports as values:
+// numbers, booleans and strings.
+func isScalar(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	switch basic.Kind() {
+	case types.Bool, types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint8, types.Uint16, types.Uint32, types.Uint64,
+		types.Float32, types.Float64, types.Complex64, types.Complex128, types.String:
+		return true
+	}
+	return false
+}
+
+// isPrimitive returns true for the types, pointers to which are reported as values with the default pointer policy.
+func isPrimitive(typ types.Type) bool {
+	_, ok := typ.Underlying().(*types.Basic)
+	return ok
+}
+
+func isTime(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time"
+}
+
+// hasField returns true, if the type, or the type it points to, is a struct with the field.
+func hasField(typ types.Type, name string) bool {
+	for {
+		p, ok := typ.Underlying().(*types.Pointer)
+		if !ok {
+			break
+		}
+		typ = p.Elem()
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *reference) writeMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s\n\n", r.name)
+	if r.doc != "" {
+		fmt.Fprintf(&sb, "%s\n\n", strings.TrimSpace(r.doc))
+	}
+	sb.WriteString("| Key | Type | Default | Description |\n| --- | --- | --- | --- |\n")
+	for _, row := range r.rows {
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n",
+			markdownCode(row.key), markdownCode(row.typ), markdownCode(row.defValue), markdownCell(row.doc))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
+
+func (r *reference) writeHTML(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<h2>%s</h2>\n", html.EscapeString(r.name))
+	if r.doc != "" {
+		fmt.Fprintf(&sb, "<p>%s</p>\n", html.EscapeString(strings.TrimSpace(r.doc)))
+	}
+	sb.WriteString("<table>\n<tr><th>Key</th><th>Type</th><th>Default</th><th>Description</th></tr>\n")
+	for _, row := range r.rows {
+		fmt.Fprintf(&sb, "<tr><td><code>%s</code></td><td><code>%s</code></td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(row.key), html.EscapeString(row.typ), htmlCode(row.defValue), html.EscapeString(row.doc))
+	}
+	sb.WriteString("</table>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func htmlCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "<code>" + html.EscapeString(s) + "</code>"
+}
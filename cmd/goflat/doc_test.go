@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/avdva/goflat"
+	"github.com/avdva/goflat/cmd/goflat/testdata/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocGolden(t *testing.T) {
+	a := assert.New(t)
+	golden, err := os.ReadFile(filepath.Join("testdata", "config.md"))
+	a.NoError(err)
+	var buf bytes.Buffer
+	a.NoError(runDoc([]string{"-dir", filepath.Join("testdata", "config"), "Config"}, &buf))
+	a.Equal(string(golden), buf.String(), "run goflat doc -dir testdata/config Config > testdata/config.md to update the golden file")
+}
+
+func TestDocMatchesSchema(t *testing.T) {
+	a := assert.New(t)
+	ref, err := loadReference(filepath.Join("testdata", "config"), "Config", "default", ".", false)
+	a.NoError(err)
+	var keys, paths []string
+	for _, row := range ref.rows {
+		keys = append(keys, row.key)
+	}
+	for _, field := range goflat.Schema(reflect.TypeOf(config.Config{})) {
+		paths = append(paths, field.Path)
+	}
+	a.Equal(paths, keys)
+
+	var buf bytes.Buffer
+	a.NoError(runDoc([]string{"-dir", filepath.Join("testdata", "config"), "-strings", "Config"}, &buf))
+	a.Contains(buf.String(), "| `Started` | `time.Time` |  | Started is the start time. |\n")
+}
+
+func TestDocHTML(t *testing.T) {
+	a := assert.New(t)
+	var buf bytes.Buffer
+	a.NoError(runDoc([]string{"-dir", filepath.Join("testdata", "config"), "-format", "html", "-delimiter", "/", "Config"}, &buf))
+	a.Contains(buf.String(), "<p>Config is the service configuration.\nIt is loaded from a file.</p>")
+	a.Contains(buf.String(), "<tr><td><code>port</code></td><td><code>*int</code></td><td><code>8080</code></td><td>Port to listen on | 0 picks a random port.</td></tr>")
+	a.Contains(buf.String(), "<tr><td><code>Tree/Children/*</code></td><td><code>Node</code></td><td></td><td>Same as `Tree`.</td></tr>")
+}
+
+func TestDocErrors(t *testing.T) {
+	a := assert.New(t)
+	dir := filepath.Join("testdata", "config")
+	a.Error(runDoc(nil, &bytes.Buffer{}))
+	a.Error(runDoc([]string{"-dir", dir, "None"}, &bytes.Buffer{}))
+	a.Error(runDoc([]string{"-dir", dir, "-format", "pdf", "Config"}, &bytes.Buffer{}))
+	generic := t.TempDir()
+	a.NoError(os.WriteFile(filepath.Join(generic, "p.go"), []byte("package p\n\ntype T[X any] struct{ A X }\n"), 0o644))
+	a.Error(runDoc([]string{"-dir", generic, "T"}, &bytes.Buffer{}))
+}
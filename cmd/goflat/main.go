@@ -0,0 +1,49 @@
+// Command goflat provides tools for the types flattened with goflat.
+//
+// The doc command prints a reference of the keys Flatten produces for a struct type,
+// with their types, defaults and doc comments, as a Markdown or HTML table:
+//
+//	goflat doc [-dir package-dir] [-format markdown|html] [-default-tag default] [-delimiter .] [-strings] Type
+//
+// For example, for
+//
+//	// Config is the service configuration.
+//	type Config struct {
+//		// Name is the service name.
+//		Name    string   `goflat:"name" default:"app"`
+//		Servers []Server `goflat:"servers,key=Host"`
+//	}
+//
+// it prints the rows for "name" and "servers[Host=*].*" keys. Map keys and slice elements are shown as "*",
+// recursive types are referred to instead of being expanded again.
+// The keys are the ones goflat.Schema lists with the default options, so time.Time fields,
+// which have no exported fields, have no keys. With -strings flag they are documented as single keys,
+// as FlattenStrings reports them.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "doc":
+		err = runDoc(os.Args[2:], os.Stdout)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "goflat:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: goflat doc [flags] Type")
+	os.Exit(2)
+}
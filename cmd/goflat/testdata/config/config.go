@@ -0,0 +1,44 @@
+// Package config is a sample package for goflat doc tests.
+package config
+
+import "time"
+
+// Config is the service configuration.
+// It is loaded from a file.
+type Config struct {
+	// Name is the service name.
+	Name string `goflat:"name" default:"app"`
+	// Port to listen on | 0 picks a random port.
+	Port    *int          `goflat:"port" default:"8080"`
+	Timeout time.Duration `default:"5s"` // Timeout of the requests.
+	// Started is the start time.
+	Started time.Time
+	// Labels are added to the metrics.
+	Labels  map[string]string `goflat:"labels"`
+	Servers []Server          `goflat:"servers,key=Host"`
+	Cert    []byte
+	Tree    Node
+	Extra   interface{}
+	Ignored string `goflat:"-"`
+	Limits
+	private string
+}
+
+// Server is an upstream server.
+type Server struct {
+	// Host is the address of the server.
+	Host   string
+	Weight float64 `default:"1"`
+}
+
+// Limits are the request limits.
+type Limits struct {
+	// RPS is the maximum number of requests per second.
+	RPS int
+}
+
+// Node is a tree node.
+type Node struct {
+	Value    string
+	Children []*Node
+}
@@ -0,0 +1,285 @@
+package goflat
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when there is no value for a given path.
+	ErrNotFound = errors.New("goflat: value not found")
+	// ErrConversion is returned when a value can not be converted to a requested type.
+	ErrConversion = errors.New("goflat: conversion error")
+)
+
+// ConversionError describes a failed conversion of a value to a given type.
+type ConversionError struct {
+	Path  string
+	Value interface{}
+	Type  reflect.Type
+	Err   error
+}
+
+func (e *ConversionError) Error() string {
+	msg := fmt.Sprintf("goflat: cannot convert %T to %v", e.Value, e.Type)
+	if e.Path != "" {
+		msg = fmt.Sprintf("goflat: %q: cannot convert %T to %v", e.Path, e.Value, e.Type)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap allows to match the error with ErrConversion.
+func (e *ConversionError) Unwrap() error {
+	return ErrConversion
+}
+
+// convert converts src to a value of type typ.
+// It follows pointers in src, allocates pointers for typ, converts between numeric types
+// without losing precision, and parses strings into numbers, booleans and RFC 3339 times.
+func convert(src interface{}, typ reflect.Type) (reflect.Value, error) {
+	return convertFormatted(src, typ, nil)
+}
+
+// convertFormatted is like convert, but if f is not nil, it parses strings into times with f.TimeLayout,
+// and decodes byte slices and arrays with f.BytesEncoding, as f formats them.
+func convertFormatted(src interface{}, typ reflect.Type, f *ValueFormatter) (reflect.Value, error) {
+	sv := reflect.ValueOf(src)
+	for sv.Kind() == reflect.Pointer || sv.Kind() == reflect.Interface {
+		if sv.Type().AssignableTo(typ) {
+			break
+		}
+		sv = sv.Elem()
+	}
+	res, err := convertValue(sv, typ, f)
+	if err != nil {
+		return reflect.Value{}, &ConversionError{Value: src, Type: typ, Err: err}
+	}
+	return res, nil
+}
+
+func convertValue(sv reflect.Value, typ reflect.Type, f *ValueFormatter) (reflect.Value, error) {
+	if !sv.IsValid() {
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+			return reflect.Zero(typ), nil
+		}
+		return reflect.Value{}, errors.New("nil value")
+	}
+	if sv.Type().AssignableTo(typ) {
+		res := reflect.New(typ).Elem()
+		res.Set(copyValue(sv))
+		return res, nil
+	}
+	if typ.Kind() == reflect.Pointer {
+		elem, err := convertValue(sv, typ.Elem(), f)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		res := reflect.New(typ.Elem())
+		res.Elem().Set(elem)
+		return res, nil
+	}
+	res := reflect.New(typ).Elem()
+	if typ == timeType && sv.Kind() == reflect.String {
+		layout := time.RFC3339Nano
+		if f != nil && f.TimeLayout != "" {
+			layout = f.TimeLayout
+		}
+		t, err := time.Parse(layout, sv.String())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		res.Set(reflect.ValueOf(t))
+		return res, nil
+	}
+	if f != nil && isBytes(typ) && sv.Kind() == reflect.String {
+		if err := f.parseBytes(res, sv.String()); err != nil {
+			return reflect.Value{}, err
+		}
+		return res, nil
+	}
+	var err error
+	switch kind := typ.Kind(); {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		var i int64
+		if i, err = toInt(sv); err == nil {
+			if res.OverflowInt(i) {
+				return reflect.Value{}, errors.New("value out of range")
+			}
+			res.SetInt(i)
+		}
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		var u uint64
+		if u, err = toUint(sv); err == nil {
+			if res.OverflowUint(u) {
+				return reflect.Value{}, errors.New("value out of range")
+			}
+			res.SetUint(u)
+		}
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		var f float64
+		if f, err = toFloat(sv); err == nil {
+			res.SetFloat(f)
+		}
+	case kind == reflect.Complex64 || kind == reflect.Complex128:
+		var c complex128
+		if c, err = toComplex(sv); err == nil {
+			res.SetComplex(c)
+		}
+	case kind == reflect.Bool:
+		var b bool
+		if b, err = toBool(sv); err == nil {
+			res.SetBool(b)
+		}
+	case kind == reflect.String:
+		var s string
+		if s, err = toString(sv); err == nil {
+			res.SetString(s)
+		}
+	default:
+		if !sv.Type().ConvertibleTo(typ) {
+			return reflect.Value{}, errors.New("incompatible types")
+		}
+		res.Set(copyValue(sv).Convert(typ))
+	}
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return res, nil
+}
+
+// copyValue returns a value, which can be used with reflect.Value.Set.
+// For values obtained from unexported fields it makes a copy of primitive types.
+func copyValue(val reflect.Value) reflect.Value {
+	if val.CanInterface() {
+		return val
+	}
+	res := reflect.New(val.Type()).Elem()
+	switch kind := val.Kind(); {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		res.SetInt(val.Int())
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		res.SetUint(val.Uint())
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		res.SetFloat(val.Float())
+	case kind == reflect.Complex64 || kind == reflect.Complex128:
+		res.SetComplex(val.Complex())
+	case kind == reflect.Bool:
+		res.SetBool(val.Bool())
+	case kind == reflect.String:
+		res.SetString(val.String())
+	}
+	return res
+}
+
+func toInt(val reflect.Value) (int64, error) {
+	switch kind := val.Kind(); {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return val.Int(), nil
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		if u := val.Uint(); u <= math.MaxInt64 {
+			return int64(u), nil
+		}
+		return 0, errors.New("value out of range")
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		f := val.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, errors.New("not an integer value")
+		}
+		return int64(f), nil
+	case kind == reflect.String:
+		return strconv.ParseInt(val.String(), 10, 64)
+	}
+	return 0, errors.New("not a number")
+}
+
+func toUint(val reflect.Value) (uint64, error) {
+	switch kind := val.Kind(); {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		if i := val.Int(); i >= 0 {
+			return uint64(i), nil
+		}
+		return 0, errors.New("negative value")
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return val.Uint(), nil
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		f := val.Float()
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+			return 0, errors.New("not an unsigned integer value")
+		}
+		return uint64(f), nil
+	case kind == reflect.String:
+		return strconv.ParseUint(val.String(), 10, 64)
+	}
+	return 0, errors.New("not a number")
+}
+
+func toFloat(val reflect.Value) (float64, error) {
+	switch kind := val.Kind(); {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return float64(val.Int()), nil
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return float64(val.Uint()), nil
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		return val.Float(), nil
+	case kind == reflect.String:
+		return strconv.ParseFloat(val.String(), 64)
+	}
+	return 0, errors.New("not a number")
+}
+
+func toComplex(val reflect.Value) (complex128, error) {
+	switch kind := val.Kind(); {
+	case kind == reflect.Complex64 || kind == reflect.Complex128:
+		return val.Complex(), nil
+	case kind == reflect.String:
+		return strconv.ParseComplex(val.String(), 128)
+	}
+	f, err := toFloat(val)
+	return complex(f, 0), err
+}
+
+func toBool(val reflect.Value) (bool, error) {
+	switch val.Kind() {
+	case reflect.Bool:
+		return val.Bool(), nil
+	case reflect.String:
+		return strconv.ParseBool(val.String())
+	}
+	return false, errors.New("not a boolean")
+}
+
+func toString(val reflect.Value) (string, error) {
+	switch kind := val.Kind(); {
+	case kind == reflect.String:
+		return val.String(), nil
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10), nil
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return strconv.FormatUint(val.Uint(), 10), nil
+	case kind == reflect.Float32:
+		return strconv.FormatFloat(val.Float(), 'g', -1, 32), nil
+	case kind == reflect.Float64:
+		return strconv.FormatFloat(val.Float(), 'g', -1, 64), nil
+	case kind == reflect.Complex64:
+		return strconv.FormatComplex(val.Complex(), 'g', -1, 64), nil
+	case kind == reflect.Complex128:
+		return strconv.FormatComplex(val.Complex(), 'g', -1, 128), nil
+	case kind == reflect.Bool:
+		return strconv.FormatBool(val.Bool()), nil
+	case kind == reflect.Slice && val.Type().Elem().Kind() == reflect.Uint8:
+		b := make([]byte, val.Len())
+		for i := range b {
+			b[i] = byte(val.Index(i).Uint())
+		}
+		return string(b), nil
+	}
+	return "", errors.New("not a string")
+}
@@ -0,0 +1,348 @@
+package goflat
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeOp is a kind of a change between two objects.
+type ChangeOp int8
+
+const (
+	// ChangeAdded: the path exists in the new object only.
+	ChangeAdded ChangeOp = iota
+	// ChangeRemoved: the path exists in the old object only.
+	ChangeRemoved
+	// ChangeModified: the value at the path was changed.
+	ChangeModified
+	// ChangeTypeChanged: the dynamic type of the value at the path was changed.
+	ChangeTypeChanged
+)
+
+func (op ChangeOp) String() string {
+	switch op {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	case ChangeTypeChanged:
+		return "type-changed"
+	}
+	return "ChangeOp(" + strconv.Itoa(int(op)) + ")"
+}
+
+// Change describes a difference between two objects.
+type Change struct {
+	// Path is a path of the changed value, as it would be reported by Flatten.
+	Path string
+	Op   ChangeOp
+	// Old is the old value. It is nil for added values.
+	Old interface{}
+	// New is the new value. It is nil for removed values.
+	New interface{}
+
+	elems []string
+}
+
+type differ struct {
+	o       *options
+	visited map[[2]uintptr]struct{}
+	changes []Change
+	// indexOnly disables addressing of slice elements by key fields.
+	indexOnly bool
+}
+
+// Diff compares two objects and returns a list of changes between them.
+// Both objects are walked in lockstep using the same rules as Walk.
+// If a container (a map entry, a slice element, etc.) exists in one of the objects only,
+// a single change is reported for the whole container. The same is true for a map or a slice, which is nil
+// in one of the objects only.
+// Map keys are always compared in sorted order, so the result is deterministic.
+// FloatTolerance and IgnorePaths options can be used to fine-tune the comparison.
+func Diff(a, b interface{}, opts ...Option) []Change {
+	return newDiffer(makeOptions(opts...)).run(a, b)
+}
+
+func newDiffer(o *options) *differ {
+	return &differ{
+		o:       o,
+		visited: make(map[[2]uintptr]struct{}),
+	}
+}
+
+func (d *differ) run(a, b interface{}) []Change {
+	d.diff(reflect.ValueOf(a), reflect.ValueOf(b), make([]string, 0, 16), "")
+	return d.changes
+}
+
+func (d *differ) diff(a, b reflect.Value, path []string, key string) {
+	if d.ignored(path) {
+		return
+	}
+	for a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return
+	case !a.IsValid():
+		d.add(path, ChangeAdded, a, b)
+		return
+	case !b.IsValid():
+		d.add(path, ChangeRemoved, a, b)
+		return
+	case a.Type() != b.Type():
+		d.add(path, ChangeTypeChanged, a, b)
+		return
+	}
+	if (d.o.isLeaf != nil && d.o.isLeaf(a.Type())) || (!d.o.expandBytes && isBytes(a.Type())) {
+		d.compare(a, b, path)
+		return
+	}
+	switch a.Kind() {
+	case reflect.Pointer:
+		d.diffPointer(a, b, path)
+	case reflect.Struct:
+		for _, field := range d.o.structFields(a.Type()) {
+			d.diff(a.Field(field.index), b.Field(field.index), append(path, field.name), field.key)
+		}
+	case reflect.Map:
+		d.diffMap(a, b, path)
+	case reflect.Slice, reflect.Array:
+		if key == "" {
+			key = d.o.sliceKeys[a.Type().Elem()]
+		}
+		d.diffSliceOrArray(a, b, path, key)
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+	default:
+		d.compare(a, b, path)
+	}
+}
+
+func (d *differ) diffPointer(a, b reflect.Value, path []string) {
+	if a.IsNil() || b.IsNil() {
+		if a.IsNil() != b.IsNil() {
+			d.add(path, ChangeModified, a, b)
+		}
+		return
+	}
+	if d.enter(a, b) {
+		defer d.leave(a, b)
+		d.diff(a.Elem(), b.Elem(), path, "")
+	}
+}
+
+func (d *differ) diffMap(a, b reflect.Value, path []string) {
+	if d.nilContainer(a, b, path) {
+		return
+	}
+	if d.o.setMode != SetNone && isSet(a.Type()) {
+		aMembers, bMembers := setMembers(a), setMembers(b)
+		if d.o.setMode == SetAsValue {
+			d.compare(aMembers, bMembers, path)
+		} else {
+			d.diffIndexed(aMembers, bMembers, path, "")
+		}
+		return
+	}
+	if a.Type().Key().Kind() != reflect.String || !d.enter(a, b) {
+		return
+	}
+	defer d.leave(a, b)
+	keys := make([]string, 0, a.Len()+b.Len())
+	for _, k := range a.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	for _, k := range b.MapKeys() {
+		if !a.MapIndex(k).IsValid() {
+			keys = append(keys, k.String())
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		kv := reflect.ValueOf(k).Convert(a.Type().Key())
+		d.diff(a.MapIndex(kv), b.MapIndex(kv), append(path, k), "")
+	}
+}
+
+func (d *differ) diffSliceOrArray(a, b reflect.Value, path []string, key string) {
+	if d.o.shouldCollapse(a.Type(), path) {
+		d.compare(a, b, path)
+		return
+	}
+	if a.Kind() == reflect.Slice {
+		if d.nilContainer(a, b, path) || !d.enter(a, b) {
+			return
+		}
+		defer d.leave(a, b)
+	}
+	if key != "" && !d.indexOnly {
+		aSegments, aErr := d.o.keySegments(a, path, key)
+		bSegments, bErr := d.o.keySegments(b, path, key)
+		if aErr == nil && bErr == nil {
+			d.diffKeyed(a, b, path, aSegments, bSegments)
+			return
+		}
+	} else {
+		key = ""
+	}
+	d.diffIndexed(a, b, path, key)
+}
+
+// nilContainer reports a single modification for a map or a slice, which is nil in one of the objects only,
+// instead of the changes of its elements, which have no parent in the other object.
+func (d *differ) nilContainer(a, b reflect.Value, path []string) bool {
+	if a.IsNil() == b.IsNil() {
+		return false
+	}
+	d.add(path, ChangeModified, a, b)
+	return true
+}
+
+// diffIndexed compares the elements of slices or arrays by index. key is set, if they should have been
+// addressed by a key field, see indexSegment.
+func (d *differ) diffIndexed(a, b reflect.Value, path []string, key string) {
+	for i := 0; i < a.Len() || i < b.Len(); i++ {
+		var aElem, bElem reflect.Value
+		if i < a.Len() {
+			aElem = a.Index(i)
+		}
+		if i < b.Len() {
+			bElem = b.Index(i)
+		}
+		d.diffElem(aElem, bElem, append(path, d.o.indexSegment(i, key)))
+	}
+}
+
+func (d *differ) diffKeyed(a, b reflect.Value, path []string, aSegments, bSegments []string) {
+	path = d.o.keyedParent(path)
+	bIndices := make(map[string]int, len(bSegments))
+	for i, segment := range bSegments {
+		bIndices[segment] = i
+	}
+	aIndices := make(map[string]int, len(aSegments))
+	for i, segment := range aSegments {
+		aIndices[segment] = i
+		var bElem reflect.Value
+		if j, found := bIndices[segment]; found {
+			bElem = b.Index(j)
+		}
+		d.diffElem(a.Index(i), bElem, append(path, segment))
+	}
+	for j, segment := range bSegments {
+		if _, found := aIndices[segment]; !found {
+			d.diffElem(reflect.Value{}, b.Index(j), append(path, segment))
+		}
+	}
+}
+
+// diffElem compares slice elements. Unlike diff, it treats absent elements as added or removed
+// even if they are nil interfaces or pointers.
+func (d *differ) diffElem(a, b reflect.Value, path []string) {
+	switch {
+	case !a.IsValid():
+		if !d.ignored(path) {
+			d.add(path, ChangeAdded, a, b)
+		}
+	case !b.IsValid():
+		if !d.ignored(path) {
+			d.add(path, ChangeRemoved, a, b)
+		}
+	default:
+		d.diff(a, b, path, "")
+	}
+}
+
+// compare reports a modification, if the values are not equal.
+func (d *differ) compare(a, b reflect.Value, path []string) {
+	if kind := a.Kind(); d.o.floatTolerance > 0 && (kind == reflect.Float32 || kind == reflect.Float64) {
+		if math.Abs(a.Float()-b.Float()) > d.o.floatTolerance {
+			d.add(path, ChangeModified, a, b)
+		}
+		return
+	}
+	if !reflect.DeepEqual(valueInterface(a), valueInterface(b)) {
+		d.add(path, ChangeModified, a, b)
+	}
+}
+
+// enter marks a pair of containers as visited. It returns false, if they are already being compared,
+// or if they share the same memory.
+func (d *differ) enter(a, b reflect.Value) bool {
+	pair := [2]uintptr{a.Pointer(), b.Pointer()}
+	if _, found := d.visited[pair]; found || (pair[0] == pair[1] && (a.Kind() == reflect.Pointer || a.Len() == b.Len())) {
+		return false
+	}
+	d.visited[pair] = struct{}{}
+	return true
+}
+
+func (d *differ) leave(a, b reflect.Value) {
+	delete(d.visited, [2]uintptr{a.Pointer(), b.Pointer()})
+}
+
+func (d *differ) ignored(path []string) bool {
+	for _, pattern := range d.o.ignorePaths {
+		if matchPath(pattern, path, d.o.delimeter) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *differ) add(path []string, op ChangeOp, a, b reflect.Value) {
+	elems := make([]string, len(path))
+	copy(elems, path)
+	d.changes = append(d.changes, Change{
+		Path:  strings.Join(path, d.o.delimeter),
+		Op:    op,
+		Old:   valueInterface(a),
+		New:   valueInterface(b),
+		elems: elems,
+	})
+}
+
+// valueInterface returns val.Interface(), if possible.
+// For values obtained from unexported fields it returns copies of primitive values, slices and arrays of primitives,
+// and nil for other types.
+func valueInterface(val reflect.Value) interface{} {
+	switch {
+	case !val.IsValid():
+		return nil
+	case val.CanInterface():
+		return val.Interface()
+	case isScalar(val.Kind()):
+		return copyValue(val).Interface()
+	case (val.Kind() == reflect.Slice || val.Kind() == reflect.Array) && isScalar(val.Type().Elem().Kind()):
+		return sliceInterface(val)
+	}
+	return nil
+}
+
+// isScalar returns true for numbers, booleans and strings.
+func isScalar(kind reflect.Kind) bool {
+	return isPrimitive(kind) && kind != reflect.Invalid && kind != reflect.Pointer && kind != reflect.UnsafePointer
+}
+
+// FloatTolerance option sets the maximum difference between two floats, which Diff treats as equal.
+func FloatTolerance(eps float64) Option {
+	return func(o *options) {
+		o.floatTolerance = eps
+	}
+}
+
+// IgnorePaths option makes Diff skip the values with the given paths, and everything inside them.
+// A path consists of elements separated by the delimeter, "*" matches any single element, e.g. "Items.*.UpdatedAt".
+func IgnorePaths(paths ...string) Option {
+	return func(o *options) {
+		o.ignorePaths = append(o.ignorePaths, paths...)
+	}
+}
@@ -0,0 +1,144 @@
+package goflat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/avdva/goflat/testpkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type diffItem struct {
+	ID    int
+	Price float64
+}
+
+type diffStruct struct {
+	Name   string
+	Ptr    *int
+	M      map[string]int
+	Slice  []int
+	Items  []diffItem `goflat:",key=ID"`
+	Iface  interface{}
+	Nested *diffStruct
+	Bytes  []byte
+	hidden int
+}
+
+func TestDiff(t *testing.T) {
+	one, two := 1, 2
+	a := diffStruct{
+		Name:   "a",
+		Ptr:    &one,
+		M:      map[string]int{"x": 1, "y": 2},
+		Slice:  []int{1, 2, 3},
+		Items:  []diffItem{{ID: 1, Price: 1}, {ID: 2, Price: 2}},
+		Iface:  1,
+		Bytes:  []byte{1},
+		hidden: 1,
+	}
+	b := diffStruct{
+		Name:   "b",
+		Ptr:    &two,
+		M:      map[string]int{"y": 3, "z": 4},
+		Slice:  []int{1, 5},
+		Items:  []diffItem{{ID: 2, Price: 2.5}, {ID: 3, Price: 3}},
+		Iface:  "1",
+		Nested: &diffStruct{Name: "nested"},
+		Bytes:  []byte{2},
+		hidden: 2,
+	}
+	exp := []Change{
+		{Path: "Name", Op: ChangeModified, Old: "a", New: "b"},
+		{Path: "Ptr", Op: ChangeModified, Old: 1, New: 2},
+		{Path: "M.x", Op: ChangeRemoved, Old: 1},
+		{Path: "M.y", Op: ChangeModified, Old: 2, New: 3},
+		{Path: "M.z", Op: ChangeAdded, New: 4},
+		{Path: "Slice.1", Op: ChangeModified, Old: 2, New: 5},
+		{Path: "Slice.2", Op: ChangeRemoved, Old: 3},
+		{Path: "Items[ID=1]", Op: ChangeRemoved, Old: diffItem{ID: 1, Price: 1}},
+		{Path: "Items[ID=2].Price", Op: ChangeModified, Old: 2.0, New: 2.5},
+		{Path: "Items[ID=3]", Op: ChangeAdded, New: diffItem{ID: 3, Price: 3}},
+		{Path: "Iface", Op: ChangeTypeChanged, Old: 1, New: "1"},
+		{Path: "Nested", Op: ChangeModified, Old: (*diffStruct)(nil), New: b.Nested},
+		{Path: "Bytes", Op: ChangeModified, Old: []byte{1}, New: []byte{2}},
+	}
+	act := Diff(a, b)
+	for i := range act {
+		act[i].elems = nil
+	}
+	assert.Equal(t, exp, act)
+
+	act = Diff(a, b, ExpandUnexported(true), IgnorePaths("M", "Slice"), FloatTolerance(1))
+	for i := range act {
+		act[i].elems = nil
+	}
+	assert.Equal(t, []Change{
+		exp[0], exp[1], exp[7], exp[9], exp[10], exp[11], exp[12],
+		{Path: "hidden", Op: ChangeModified, Old: 1, New: 2},
+	}, act)
+}
+
+func TestDiffOptions(t *testing.T) {
+	a := assert.New(t)
+	type floats struct {
+		F     float64
+		Items []diffItem
+	}
+	x := floats{F: 1.0, Items: []diffItem{{ID: 1, Price: 1}, {ID: 2, Price: 2}}}
+	y := floats{F: 1.05, Items: []diffItem{{ID: 2, Price: 2}, {ID: 1, Price: 1.01}}}
+	a.Empty(Diff(x, y, FloatTolerance(0.1), SliceKeyField(reflect.TypeOf(diffItem{}), "ID")))
+	a.Len(Diff(x, y, FloatTolerance(0.1)), 4)
+	changes := Diff(x, y, IgnorePaths("Items.*.ID", "F"))
+	a.Len(changes, 2)
+	a.Equal("Items.0.Price", changes[0].Path)
+	a.Equal("Items.1.Price", changes[1].Path)
+}
+
+func TestDiffEqual(t *testing.T) {
+	a := assert.New(t)
+	ts := testpkg.NewTestStruct()
+	a.Empty(Diff(ts, testpkg.NewTestStruct(), ExpandUnexported(true)))
+	a.Empty(Diff(ts, ts))
+	a.Empty(Diff(nil, nil))
+	a.Equal([]Change{{Op: ChangeAdded, New: 1, elems: []string{}}}, Diff(nil, 1))
+	a.Equal([]Change{{Op: ChangeTypeChanged, Old: 1, New: "1", elems: []string{}}}, Diff(1, "1"))
+}
+
+func TestDiffNilContainers(t *testing.T) {
+	a := assert.New(t)
+	type obj struct {
+		M map[string]int
+		S []int
+	}
+	full := obj{M: map[string]int{"a": 1, "b": 2}, S: []int{1}}
+	a.Equal([]Change{
+		{Path: "M", Op: ChangeModified, Old: map[string]int(nil), New: full.M, elems: []string{"M"}},
+		{Path: "S", Op: ChangeModified, Old: []int(nil), New: full.S, elems: []string{"S"}},
+	}, Diff(obj{}, full))
+	a.Equal([]Change{
+		{Path: "M", Op: ChangeModified, Old: full.M, New: map[string]int(nil), elems: []string{"M"}},
+		{Path: "S", Op: ChangeModified, Old: full.S, New: []int(nil), elems: []string{"S"}},
+	}, Diff(full, obj{}))
+}
+
+func TestDiffCycles(t *testing.T) {
+	a := assert.New(t)
+	type node struct {
+		Val  int
+		Next *node
+	}
+	x, y := &node{Val: 1}, &node{Val: 1}
+	x.Next, y.Next = x, y
+	a.Empty(Diff(x, y))
+	y.Val = 2
+	a.Len(Diff(x, y), 1)
+}
+
+func TestChangeOpString(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("added", ChangeAdded.String())
+	a.Equal("type-changed", ChangeTypeChanged.String())
+	a.Equal("ChangeOp(10)", ChangeOp(10).String())
+}
@@ -0,0 +1,236 @@
+package goflat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrBadSyntax is returned when a .env or a .properties file can't be parsed.
+var ErrBadSyntax = errors.New("goflat: bad syntax")
+
+// WriteDotenv writes a golang object as a .env file with variables named as by ToEnv.
+// Values, which contain characters other than letters, digits and "_-.,:/@%+", are written in double quotes,
+// where '"', '\\', '$', '`' and line breaks are escaped with a backslash.
+func WriteDotenv(w io.Writer, obj interface{}, prefix string, opts ...Option) error {
+	bw := bufio.NewWriter(w)
+	for _, kv := range ToEnv(obj, prefix, opts...) {
+		name, value, _ := strings.Cut(kv, "=")
+		bw.WriteString(name)
+		bw.WriteByte('=')
+		bw.WriteString(quoteDotenv(value))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+func quoteDotenv(s string) string {
+	if strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.,:/@%+", r))
+	}) < 0 {
+		return s
+	}
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\', '$', '`':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// ReadDotenv reads a .env file and sets the values in dst, as FromEnv does.
+// Lines may start with "export ", '#' starts a comment, unless it is in a quoted value or it follows a non-space.
+// Values in double quotes may contain the escapes written by WriteDotenv, values in single quotes are taken as is.
+// Quoted values may span several lines. Variables are not expanded.
+// It returns the sorted names of the variables with the prefix, which don't match any path.
+func ReadDotenv(r io.Reader, dst interface{}, prefix string, opts ...Option) (unknown []string, err error) {
+	environ, err := parseDotenv(r)
+	if err != nil {
+		return nil, err
+	}
+	return FromEnv(dst, prefix, environ, opts...)
+}
+
+// parseDotenv returns the variables from a .env file as "NAME=value" pairs.
+func parseDotenv(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &dotenvParser{src: string(data), line: 1}
+	var environ []string
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return environ, nil
+		}
+		if p.peek() == '#' || p.peek() == '\n' {
+			p.skipLine()
+			continue
+		}
+		name, value, err := p.parseVar()
+		if err != nil {
+			return nil, err
+		}
+		environ = append(environ, name+"="+value)
+	}
+}
+
+type dotenvParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *dotenvParser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *dotenvParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skipSpace skips spaces, tabs and carriage returns, but not line feeds.
+func (p *dotenvParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t' || p.peek() == '\r') {
+		p.next()
+	}
+}
+
+func (p *dotenvParser) skipLine() {
+	for !p.eof() && p.next() != '\n' {
+	}
+}
+
+func (p *dotenvParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrBadSyntax, p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *dotenvParser) parseVar() (name, value string, err error) {
+	start := p.pos
+	for !p.eof() && p.peek() != '=' && p.peek() != '\n' {
+		p.next()
+	}
+	name = strings.TrimSpace(p.src[start:p.pos])
+	if strings.HasPrefix(name, "export ") {
+		name = strings.TrimSpace(name[len("export "):])
+	}
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", p.errorf("bad variable name %q", name)
+	}
+	if p.eof() || p.peek() != '=' {
+		return "", "", p.errorf("expected '=' after %s", name)
+	}
+	p.next()
+	p.skipSpace()
+	if p.eof() {
+		return name, "", nil
+	}
+	switch p.peek() {
+	case '"':
+		value, err = p.parseDoubleQuoted()
+	case '\'':
+		value, err = p.parseSingleQuoted()
+	default:
+		return name, p.parseUnquoted(), nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	// only a comment may follow a quoted value.
+	p.skipSpace()
+	if !p.eof() && p.peek() != '\n' && p.peek() != '#' {
+		return "", "", p.errorf("unexpected %q after the value of %s", p.peek(), name)
+	}
+	p.skipLine()
+	return name, value, nil
+}
+
+// parseUnquoted returns the rest of the line up to a comment. The value always follows '=', so p.pos > 0.
+func (p *dotenvParser) parseUnquoted() string {
+	start := p.pos
+	end := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		c := p.next()
+		if c == '#' && (p.src[p.pos-2] == ' ' || p.src[p.pos-2] == '\t') {
+			p.skipLine()
+			break
+		}
+		end = p.pos
+	}
+	if !p.eof() && p.peek() == '\n' {
+		p.next()
+	}
+	return strings.TrimRight(p.src[start:end], " \t\r")
+}
+
+func (p *dotenvParser) parseDoubleQuoted() (string, error) {
+	line := p.line
+	p.next()
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.next()
+		switch c {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if p.eof() {
+				break
+			}
+			switch e := p.next(); e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\', '$', '`':
+				sb.WriteByte(e)
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(e)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	p.line = line
+	return "", p.errorf("unterminated quoted value")
+}
+
+func (p *dotenvParser) parseSingleQuoted() (string, error) {
+	line := p.line
+	p.next()
+	start := p.pos
+	for !p.eof() {
+		if p.next() == '\'' {
+			return p.src[start : p.pos-1], nil
+		}
+	}
+	p.line = line
+	return "", p.errorf("unterminated quoted value")
+}
@@ -0,0 +1,109 @@
+package goflat
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDotenv(t *testing.T) {
+	a := assert.New(t)
+	port := 80
+	src := envConfig{
+		Name:    "say \"hi\"\n\t$HOME `x` \\ # not a comment",
+		Port:    &port,
+		Timeout: time.Second,
+		Started: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Labels: map[string]string{"env": "prod", "a-b": "", "quote": "it's",
+			"x_y": "1", "x.y": "2", "_": "3"},
+		Servers:  []envServer{{Host: "h_1.example", Weight: 1.5}},
+		MaxConns: 10,
+		Root:     schemaNode{Value: 1, Next: &schemaNode{Value: 2, Children: map[string]schemaNode{"c_d": {Value: 3}}}},
+	}
+	var buf bytes.Buffer
+	a.NoError(WriteDotenv(&buf, src, "app"))
+	out := buf.String()
+	a.Contains(out, "APP_PORT=80\n")
+	a.Contains(out, "APP_LABELS_A__2DB=\n")
+	a.Contains(out, "APP_LABELS_QUOTE=\"it's\"\n")
+	a.Contains(out, "APP_LABELS_X_Y=1\n")
+	a.Contains(out, "APP_LABELS_X__2EY=2\n")
+	a.Contains(out, "APP_MAX_CONNS=10\n")
+	a.Contains(out, "APP_SERVERS_H_1__2EEXAMPLE_WEIGHT=1.5\n")
+	a.Contains(out, `APP_NAME="say \"hi\"\n\t\$HOME \`+"`x\\` \\\\ # not a comment\"\n")
+
+	var dst envConfig
+	unknown, err := ReadDotenv(&buf, &dst, "app")
+	a.NoError(err)
+	a.Empty(unknown)
+	a.Equal(src, dst)
+}
+
+func TestDotenvFormatter(t *testing.T) {
+	type formatted struct {
+		Bytes   []byte
+		Array   [3]byte
+		Time    time.Time
+		TimePtr *time.Time
+	}
+	a := assert.New(t)
+	tm := time.Date(2023, 1, 2, 3, 4, 0, 0, time.UTC)
+	src := formatted{Bytes: []byte{1, 2, 3}, Array: [3]byte{4, 5, 6}, Time: tm, TimePtr: &tm}
+	for _, enc := range []BytesEncoding{BytesBase64, BytesHex, BytesRaw} {
+		f := NewValueFormatter()
+		f.BytesEncoding, f.TimeLayout = enc, "2006-01-02 15:04"
+		var buf bytes.Buffer
+		a.NoError(WriteDotenv(&buf, src, "app", WithValueFormatter(f)))
+		a.Contains(buf.String(), "APP_TIME=\"2023-01-02 03:04\"\n")
+		var dst formatted
+		unknown, err := ReadDotenv(&buf, &dst, "app", WithValueFormatter(f))
+		a.NoError(err)
+		a.Empty(unknown)
+		a.Equal(src, dst, "encoding %d", enc)
+	}
+}
+
+func TestReadDotenv(t *testing.T) {
+	a := assert.New(t)
+	input := `# comment
+export APP_NAME = plain value # comment
+APP_LABELS_A='single \n "quoted"'  # comment
+APP_LABELS_B="multi
+line"
+APP_LABELS_C=#value
+APP_LABELS_D= # empty
+APP_LABELS_E="\x"
+APP_OTHER=1
+
+OTHER=2
+`
+	var dst envConfig
+	unknown, err := ReadDotenv(strings.NewReader(input), &dst, "app")
+	a.NoError(err)
+	a.Equal([]string{"APP_OTHER"}, unknown)
+	a.Equal("plain value", dst.Name)
+	a.Equal(map[string]string{
+		"a": `single \n "quoted"`,
+		"b": "multi\nline",
+		"c": "#value",
+		"d": "",
+		"e": `\x`,
+	}, dst.Labels)
+
+	for _, input := range []string{
+		"A=1\nB=\"unterminated\n",
+		"A=1\nB='unterminated",
+		"A=1\nB\n",
+		"A=1\n=1\n",
+		"A=1\nA B=1\n",
+		"A=1\nA=\"x\" y\n",
+	} {
+		_, err := ReadDotenv(strings.NewReader(input), &dst, "")
+		a.True(errors.Is(err, ErrBadSyntax), "%q: %v", input, err)
+		a.Contains(err.Error(), "line 2", input)
+	}
+}
@@ -0,0 +1,143 @@
+package goflat
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Walker is implemented by the types with WalkGoflat methods generated by goflat-gen.
+// Walk uses the generated method instead of reflection to visit the fields of such types.
+// The methods are promoted from embedded fields, so Walk uses them only for the types T,
+// which also have a GoflatGenerated(*T) method, see GeneratedMarker.
+type Walker interface {
+	WalkGoflat(e Emitter) bool
+}
+
+// GeneratedMarker is the name of the method, which goflat-gen generates for a type T as
+//
+//	func (T) GoflatGenerated(*T) {}
+//
+// If the method is promoted from an embedded field, its parameter type is not a pointer to the outer type.
+const GeneratedMarker = "GoflatGenerated"
+
+// Emitter receives the fields of a struct from a generated WalkGoflat method.
+// The methods return false, if the walk should be stopped.
+type Emitter interface {
+	// ExpandUnexported returns true, if unexported fields should be visited.
+	ExpandUnexported() bool
+	// Emit reports a field of a basic type: a number, a boolean or a string.
+	Emit(name string, value interface{}) bool
+	// Walk visits an exported field of any other type with reflection.
+	// key is a key field name for slices, set by `goflat:",key=Field"` tag.
+	Walk(name, key string, value interface{}) bool
+	// WalkField visits an unexported field of parent, which is not of a basic type, with reflection.
+	WalkField(name, key string, parent interface{}, index int) bool
+	// Direct returns true, if the generated code may visit slices, arrays, string-keyed maps,
+	// pointers to structs with generated methods, and such structs itself. Otherwise it calls Walk for them,
+	// as the options change the way they are visited.
+	Direct() bool
+	// EmitNil reports a nil slice or map, if AddNilContainers option is set.
+	EmitNil(name string) bool
+	// Enter appends name to the path of the values, which the generated code visits until the matching Leave call.
+	// ref is a pointer, a map, or a pointer to the first element of a slice, which is being entered, or nil.
+	// Enter returns false, if ref is already being visited, and the value should be skipped.
+	// Leave may be omitted, if the walk is stopped.
+	Enter(name string, ref interface{}) bool
+	// Leave removes the path element added by Enter.
+	Leave(ref interface{})
+}
+
+var walkerType = reflect.TypeOf((*Walker)(nil)).Elem()
+
+// generatedTypes caches if types have their own generated methods.
+var generatedTypes sync.Map
+
+// hasGenerated returns true, if typ implements Walker, and the methods are not promoted from an embedded field.
+func hasGenerated(typ reflect.Type) bool {
+	if implements, found := generatedTypes.Load(typ); found {
+		return implements.(bool)
+	}
+	implements := false
+	if typ.Implements(walkerType) {
+		marker, found := typ.MethodByName(GeneratedMarker)
+		// the first parameter is the receiver.
+		implements = found && marker.Type.NumIn() == 2 && marker.Type.In(1) == reflect.PointerTo(typ)
+	}
+	generatedTypes.Store(typ, implements)
+	return implements
+}
+
+// visitGenerated visits the fields of a struct with its generated WalkGoflat method.
+func (w *walker) visitGenerated(val reflect.Value, path []string) (cont bool) {
+	prev := w.genPath
+	w.genPath = path
+	// unlike Interface, Addr doesn't copy the struct.
+	if val.CanAddr() {
+		cont = val.Addr().Interface().(Walker).WalkGoflat(w)
+	} else {
+		cont = val.Interface().(Walker).WalkGoflat(w)
+	}
+	w.genPath = prev
+	return cont
+}
+
+func (w *walker) ExpandUnexported() bool {
+	return w.o.expandUnexported
+}
+
+func (w *walker) Emit(name string, value interface{}) bool {
+	path := append(w.genPath, name)
+	if w.o.isLeaf != nil || w.rcb != nil {
+		return w.visit(reflect.ValueOf(value), path)
+	}
+	return w.cb(path, value)
+}
+
+func (w *walker) Walk(name, key string, value interface{}) bool {
+	return w.visitField(reflect.ValueOf(value), append(w.genPath, name), key)
+}
+
+func (w *walker) WalkField(name, key string, parent interface{}, index int) bool {
+	return w.visitField(reflect.ValueOf(parent).Field(index), append(w.genPath, name), key)
+}
+
+func (w *walker) Direct() bool {
+	o := w.o
+	return w.rcb == nil && w.ccb == nil && o.isLeaf == nil && !o.expandBytes && !o.sortMapKeys &&
+		o.collapseMode == CollapseNone && o.setMode == SetNone && len(o.sliceKeys) == 0 &&
+		o.pointerFollowPolicy == PointerPolicyPrimitivePointer
+}
+
+func (w *walker) EmitNil(name string) bool {
+	if w.o.addNilContainers {
+		return w.emit(append(w.genPath, name), nil)
+	}
+	return true
+}
+
+func (w *walker) Enter(name string, ref interface{}) bool {
+	if ref != nil {
+		ptr := reflect.ValueOf(ref).Pointer()
+		if _, found := w.visited[ptr]; found {
+			return false
+		}
+		w.visited[ptr] = struct{}{}
+	}
+	w.genPath = append(w.genPath, name)
+	return true
+}
+
+func (w *walker) Leave(ref interface{}) {
+	if ref != nil {
+		delete(w.visited, reflect.ValueOf(ref).Pointer())
+	}
+	w.genPath = w.genPath[:len(w.genPath)-1]
+}
+
+// visitField visits a struct field. key is a key field name for slice and array fields.
+func (w *walker) visitField(val reflect.Value, path []string, key string) bool {
+	if key != "" {
+		return w.visitSliceOrArray(val, path, key)
+	}
+	return w.visit(val, path)
+}
@@ -0,0 +1,182 @@
+package goflat
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvSeparator option sets a separator of path elements in environment variable names. '_' is the default separator.
+// The default separator is also used, if sep is empty, or contains "__", which starts the escapes in the names,
+// or '=', which ends the names, see ToEnv.
+func EnvSeparator(sep string) Option {
+	return func(o *options) {
+		o.envSeparator = sep
+	}
+}
+
+func (o *options) envSep() string {
+	if o.envSeparator == "" || strings.Contains(o.envSeparator, "__") || strings.Contains(o.envSeparator, "=") {
+		return "_"
+	}
+	return o.envSeparator
+}
+
+// ToEnv flattens a golang object into sorted environment variables like "APP_S_M_K=123".
+// Names consist of the prefix and the path elements joined with the separator set by EnvSeparator option.
+// They are upper-cased, the characters other than ASCII letters, digits and '_' are escaped
+// as "__" followed by two hex digits for each byte, e.g. "a.b" becomes "A__2EB". '_' is escaped only
+// at the start or at the end of an element, or next to another '_', so "__" always starts an escape.
+// Slice elements addressed by a key field are named by the key value, as with KeyStyleSegment,
+// e.g. "APP_SERVERS_H1_WEIGHT" for "Servers[Host=h1].Weight".
+//
+// Note, that the names are upper-cased, so FromEnv can't restore the case of map keys and key field values,
+// and lower-cases them.
+//
+// Values are formatted as by FlattenStrings, nil values are skipped.
+func ToEnv(obj interface{}, prefix string, opts ...Option) []string {
+	o := makeEnvOptions(opts...)
+	var env []string
+	o.formatLeaves(obj, func(path []string, value string) {
+		env = append(env, o.envName(prefix, path)+"="+value)
+	})
+	sort.Strings(env)
+	return env
+}
+
+// envDelimeter joins the path elements in ToEnv and FromEnv. The delimeter set by WithDelimeter doesn't appear
+// in the variable names, so the key values containing it can still address slice elements, see CheckKeys.
+const envDelimeter = "\x00"
+
+// makeEnvOptions is like makeStringOptions, but sets up the path elements, as they appear in the variable names.
+func makeEnvOptions(opts ...Option) *options {
+	o := makeStringOptions(opts...)
+	o.delimeter = envDelimeter
+	o.keyStyle = KeyStyleSegment
+	return o
+}
+
+// formatLeaves calls fn for each non-nil value in obj, formatted as by FlattenStrings.
+// o must be made by makeStringOptions.
+func (o *options) formatLeaves(obj interface{}, fn func(path []string, value string)) {
+	w := newWalker(func(path []string, value interface{}) bool {
+		if !isNilValue(value) {
+			fn(path, o.formatter.Format(value))
+		}
+		return true
+	}, o)
+	w.run(reflect.ValueOf(obj))
+}
+
+// isNilValue returns true for nil and nil pointers, maps and slices.
+func isNilValue(v interface{}) bool {
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		return val.IsNil()
+	}
+	return false
+}
+
+// envName returns an environment variable name for the path.
+func (o *options) envName(prefix string, path []string) string {
+	name := o.envNaming().name(path)
+	if prefix == "" {
+		return name
+	}
+	if name == "" {
+		return strings.ToUpper(prefix)
+	}
+	return strings.ToUpper(prefix) + o.envSep() + name
+}
+
+// envNaming returns the naming of environment variables. Names are matched upper-cased.
+func (o *options) envNaming() *pathNaming {
+	return &pathNaming{sep: o.envSep(), escape: escapeEnv, unescape: unescapeEnv, wildcard: envWildcard}
+}
+
+// envUnit matches a letter, a digit or an escape. envWildcard matches an escaped map key or key field value:
+// units with single '_' between them.
+const (
+	envUnit     = "(?:[A-Z0-9]|__[0-9A-F]{2})"
+	envWildcard = "(" + envUnit + "(?:_?" + envUnit + ")*?)"
+)
+
+func escapeEnv(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= 'a' && c <= 'z':
+			sb.WriteByte(c - 'a' + 'A')
+		case (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'):
+			sb.WriteByte(c)
+		case c == '_' && i > 0 && i < len(s)-1 && s[i+1] != '_' && !strings.HasSuffix(sb.String(), "_"):
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "__%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+var envEscape = regexp.MustCompile(`__[0-9A-F]{2}|[^_]+|_`)
+
+// unescapeEnv reverses escapeEnv for the names of map keys and key field values. Letters are lower-cased,
+// as the case is lost in the variable names. A single '_' is never the start of an escape, see escapeEnv.
+func unescapeEnv(s string) string {
+	return envEscape.ReplaceAllStringFunc(s, func(part string) string {
+		if len(part) == 4 && strings.HasPrefix(part, "__") {
+			c, _ := strconv.ParseUint(part[2:], 16, 8)
+			return string([]byte{byte(c)})
+		}
+		return strings.ToLower(part)
+	})
+}
+
+// FromEnv sets the values from environment variables in dst, as Apply does.
+// environ is a list of "NAME=value" pairs, like the one returned by os.Environ.
+// Only the variables, whose names start with the prefix and the separator, are used. Names are matched
+// case-insensitively against the paths, which Flatten may produce for dst, see Schema. If a name matches several
+// paths, e.g. a key of a map of structs contains '_', the paths with fewer map keys and shorter keys are preferred.
+// Map keys and key field values are taken from the names: they are unescaped and lower-cased,
+// e.g. both "Env" and "env" keys come back as "env". Values are converted to the types of the fields,
+// times and bytes are parsed with the TimeLayout and the BytesEncoding of the formatter set by WithValueFormatter.
+// FromEnv returns the sorted names of the variables with the prefix, which don't match any path.
+// Options, that affect naming, must match those used for ToEnv.
+func FromEnv(dst interface{}, prefix string, environ []string, opts ...Option) (unknown []string, err error) {
+	o := makeEnvOptions(opts...)
+	delim := makeOptions(opts...).delimeter
+	typ, err := dstType(dst)
+	if err != nil {
+		return nil, err
+	}
+	m := newPathMatcher(o, typ, o.envNaming())
+	namePrefix := ""
+	if prefix != "" {
+		namePrefix = strings.ToUpper(prefix) + o.envSep()
+	}
+	var changes []pathChange
+	for _, kv := range environ {
+		name, value, found := strings.Cut(kv, "=")
+		if !found || !strings.HasPrefix(strings.ToUpper(name), namePrefix) {
+			continue
+		}
+		path, ok := m.match(strings.ToUpper(name)[len(namePrefix):], 0)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		changes = append(changes, pathChange{key: joinElems(path, delim), path: path, value: value})
+	}
+	sort.Strings(unknown)
+	if len(changes) == 0 {
+		return unknown, nil
+	}
+	// key values are lower-cased in the names.
+	return unknown, o.applyChanges(dst, changes, true)
+}
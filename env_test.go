@@ -0,0 +1,182 @@
+package goflat
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type envConfig struct {
+	Name     string
+	Port     *int `goflat:"port"`
+	Timeout  time.Duration
+	Started  time.Time
+	Labels   map[string]string
+	Servers  []envServer `goflat:",key=Host"`
+	MaxConns int         `goflat:"max_conns"`
+	Root     schemaNode
+}
+
+type envServer struct {
+	Host   string
+	Weight float64
+}
+
+func TestEnv(t *testing.T) {
+	a := assert.New(t)
+	port := 80
+	src := envConfig{
+		Name:     "app",
+		Port:     &port,
+		Timeout:  time.Second,
+		Started:  time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Labels:   map[string]string{"env": "prod", "a-b": "c"},
+		Servers:  []envServer{{Host: "h1", Weight: 1.5}},
+		MaxConns: 10,
+		Root:     schemaNode{Value: 1, Next: &schemaNode{Value: 2, Children: map[string]schemaNode{"c": {Value: 3}}}},
+	}
+	env := ToEnv(src, "app")
+	a.Contains(env, "APP_PORT=80")
+	a.Contains(env, "APP_LABELS_A__2DB=c")
+	a.Contains(env, "APP_SERVERS_H1_WEIGHT=1.5")
+	a.Contains(env, "APP_ROOT_NEXT_CHILDREN_C_VALUE=3")
+	a.Contains(env, "APP_MAX_CONNS=10")
+
+	var dst envConfig
+	unknown, err := FromEnv(&dst, "app", append(env, "APP_NONE=1", "OTHER=1", "app_name=lower", "NOVALUE"))
+	a.NoError(err)
+	a.Equal([]string{"APP_NONE"}, unknown)
+	a.Equal("lower", dst.Name)
+	dst.Name = src.Name
+	a.Equal(src, dst)
+
+	env = ToEnv(src, "", EnvSeparator("-"), WithKeyStyle(KeyStyleSegment))
+	a.Contains(env, "SERVERS-H1-WEIGHT=1.5")
+	a.Contains(env, "MAX_CONNS=10")
+	var dst2 envConfig
+	unknown, err = FromEnv(&dst2, "", env, EnvSeparator("-"), WithKeyStyle(KeyStyleSegment))
+	a.NoError(err)
+	a.Empty(unknown)
+	a.Equal(src, dst2)
+}
+
+func TestEnvFormatter(t *testing.T) {
+	type formatted struct {
+		Bytes   []byte
+		Array   [3]byte
+		Time    time.Time
+		TimePtr *time.Time
+	}
+	a := assert.New(t)
+	tm := time.Date(2023, 1, 2, 3, 4, 0, 0, time.UTC)
+	src := formatted{Bytes: []byte{1, 2, 3}, Array: [3]byte{4, 5, 6}, Time: tm, TimePtr: &tm}
+	for _, enc := range []BytesEncoding{BytesBase64, BytesHex, BytesRaw} {
+		f := NewValueFormatter()
+		f.BytesEncoding, f.TimeLayout = enc, "2006-01-02 15:04"
+		env := ToEnv(src, "app", WithValueFormatter(f))
+		a.Contains(env, "APP_TIME=2023-01-02 03:04")
+		var dst formatted
+		unknown, err := FromEnv(&dst, "app", env, WithValueFormatter(f))
+		a.NoError(err)
+		a.Empty(unknown)
+		a.Equal(src, dst, "encoding %d", enc)
+	}
+	env := ToEnv(src, "app")
+	a.Contains(env, "APP_BYTES=AQID")
+	var dst formatted
+	_, err := FromEnv(&dst, "app", env)
+	a.NoError(err)
+	a.Equal(src, dst)
+	_, err = FromEnv(&dst, "app", []string{"APP_ARRAY=AQI="})
+	a.True(errors.Is(err, ErrConversion), "%v", err)
+}
+
+func TestFromEnvErrors(t *testing.T) {
+	a := assert.New(t)
+	var dst envConfig
+	_, err := FromEnv(&dst, "app", []string{"APP_MAX_CONNS=x"})
+	var convErr *ConversionError
+	a.True(errors.As(err, &convErr), "%v", err)
+	a.Equal("max_conns", convErr.Path)
+	_, err = FromEnv(nil, "app", nil)
+	a.True(errors.Is(err, ErrUnaddressable))
+	m := map[string]interface{}{}
+	unknown, err := FromEnv(m, "app", []string{"APP_A_B=1", "APP_A__B=2", "APP_A_=3"})
+	a.NoError(err)
+	a.Equal([]string{"APP_A_", "APP_A__B"}, unknown)
+	a.Equal(map[string]interface{}{"a_b": "1"}, m)
+}
+
+func TestEnvSeparator(t *testing.T) {
+	type sepConfig struct {
+		MaxConns int `goflat:"max_conns"`
+		Labels   map[string]string
+	}
+	a := assert.New(t)
+	src := sepConfig{MaxConns: 1, Labels: map[string]string{"a_b": "c"}}
+	for _, sep := range []string{"", "__", "a__b", "="} {
+		// bad separators are replaced with the default one.
+		env := ToEnv(src, "app", EnvSeparator(sep))
+		a.Equal([]string{"APP_LABELS_A_B=c", "APP_MAX_CONNS=1"}, env, sep)
+		var dst sepConfig
+		unknown, err := FromEnv(&dst, "app", env, EnvSeparator(sep))
+		a.NoError(err)
+		a.Empty(unknown)
+		a.Equal(src, dst)
+	}
+	env := ToEnv(src, "app", EnvSeparator("_X_"))
+	a.Equal([]string{"APP_X_LABELS_X_A_B=c", "APP_X_MAX_CONNS=1"}, env)
+}
+
+func TestEnvCase(t *testing.T) {
+	a := assert.New(t)
+	src := envConfig{Labels: map[string]string{"Env": "Prod"}, Servers: []envServer{{Host: "H1"}}}
+	env := ToEnv(src, "app")
+	a.Contains(env, "APP_LABELS_ENV=Prod")
+	a.Contains(env, "APP_SERVERS_H1_HOST=H1")
+	var dst envConfig
+	_, err := FromEnv(&dst, "app", env)
+	a.NoError(err)
+	// the case of map keys is lost, values are kept as is.
+	a.Equal(map[string]string{"env": "Prod"}, dst.Labels)
+	a.Equal([]envServer{{Host: "H1"}}, dst.Servers)
+}
+
+func TestEnvRoundTrip(t *testing.T) {
+	type nested struct {
+		M map[string]map[string]string
+		L map[string]int
+		N schemaNode
+	}
+	a := assert.New(t)
+	src := nested{
+		M: map[string]map[string]string{"x": {"y_z": "1"}},
+		L: map[string]int{"a.b": 1, "a_b": 2, "_a": 3, "a_": 4, "a__b": 5, "a_-b": 6, "a_5fb": 7},
+		N: schemaNode{Children: map[string]schemaNode{"c_d": {Value: 3}}},
+	}
+	env := ToEnv(src, "app")
+	a.Contains(env, "APP_M_X_Y_Z=1")
+	a.Contains(env, "APP_L_A__2EB=1")
+	a.Contains(env, "APP_L_A_B=2")
+	a.Contains(env, "APP_L___5FA=3")
+	a.Contains(env, "APP_L_A__5F=4")
+	a.Contains(env, "APP_L_A__5F_B=5")
+	a.Contains(env, "APP_L_A___2DB=6")
+	a.Contains(env, "APP_L_A_5FB=7")
+	a.Contains(env, "APP_N_CHILDREN_C_D_VALUE=3")
+	var dst nested
+	unknown, err := FromEnv(&dst, "app", env)
+	a.NoError(err)
+	a.Empty(unknown)
+	a.Equal(src, dst)
+
+	// the names of nested map keys are ambiguous, the shorter outer key is preferred.
+	env = ToEnv(nested{M: map[string]map[string]string{"x_y": {"z": "1"}}}, "app")
+	a.Contains(env, "APP_M_X_Y_Z=1")
+	dst = nested{}
+	_, err = FromEnv(&dst, "app", env)
+	a.NoError(err)
+	a.Equal(map[string]map[string]string{"x": {"y_z": "1"}}, dst.M)
+}
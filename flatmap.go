@@ -0,0 +1,174 @@
+package goflat
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FlatMap is a result of flattening: a map from element's path to a value.
+type FlatMap map[string]interface{}
+
+// SortedKeys returns all the keys of the map in ascending order.
+func (m FlatMap) SortedKeys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Has returns true, if there is a value for the path.
+func (m FlatMap) Has(path string) bool {
+	_, found := m[path]
+	return found
+}
+
+// Get returns a value for the path.
+// Use Value's methods to check if the value exists and to convert it to a desired type.
+func (m FlatMap) Get(path string) Value {
+	v, found := m[path]
+	return Value{path: path, v: v, found: found}
+}
+
+// Sub returns a subtree for the prefix. The prefix and the delimeter are stripped from the keys.
+// A value stored exactly under the prefix goes to the "" key.
+// WithDelimeter option can be used if the map was built with a non-default delimeter.
+func (m FlatMap) Sub(prefix string, opts ...Option) FlatMap {
+	if prefix == "" {
+		res := make(FlatMap, len(m))
+		for k, v := range m {
+			res[k] = v
+		}
+		return res
+	}
+	o := makeOptions(opts...)
+	res := make(FlatMap)
+	for k, v := range m {
+		if k == prefix {
+			res[""] = v
+		} else if strings.HasPrefix(k, prefix) && strings.HasPrefix(k[len(prefix):], o.delimeter) {
+			res[k[len(prefix)+len(o.delimeter):]] = v
+		}
+	}
+	return res
+}
+
+// Prefixes returns all the distinct container paths of the map, i.e. keys' proper prefixes, in ascending order.
+// For example, for "S.M.k" key it returns "S" and "S.M".
+// WithDelimeter option can be used if the map was built with a non-default delimeter.
+func (m FlatMap) Prefixes(opts ...Option) []string {
+	o := makeOptions(opts...)
+	set := make(map[string]struct{})
+	for k := range m {
+		for i := strings.LastIndex(k, o.delimeter); i > 0; i = strings.LastIndex(k, o.delimeter) {
+			k = k[:i]
+			if _, found := set[k]; found {
+				break
+			}
+			set[k] = struct{}{}
+		}
+	}
+	res := make([]string, 0, len(set))
+	for k := range set {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Unflatten builds a tree of nested maps from the flat map.
+// If a path is both a value and a container (for instance, a pointer to a struct with PointerPolicyBoth),
+// the container wins.
+// WithDelimeter option can be used if the map was built with a non-default delimeter.
+func (m FlatMap) Unflatten(opts ...Option) map[string]interface{} {
+	o := makeOptions(opts...)
+	res := make(map[string]interface{})
+	for _, key := range m.SortedKeys() {
+		if key == "" {
+			continue
+		}
+		parts := strings.Split(key, o.delimeter)
+		current := res
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := current[part].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				current[part] = next
+			}
+			current = next
+		}
+		last := parts[len(parts)-1]
+		if _, isContainer := current[last].(map[string]interface{}); !isContainer {
+			current[last] = m[key]
+		}
+	}
+	return res
+}
+
+// Value is a value from a FlatMap.
+type Value struct {
+	path  string
+	v     interface{}
+	found bool
+}
+
+// Exists returns true, if the value was found in the map.
+func (v Value) Exists() bool {
+	return v.found
+}
+
+// Interface returns the value as is.
+func (v Value) Interface() interface{} {
+	return v.v
+}
+
+// Int returns the value converted to int64.
+func (v Value) Int() (int64, error) {
+	var i int64
+	return i, v.convertTo(&i)
+}
+
+// Uint returns the value converted to uint64.
+func (v Value) Uint() (uint64, error) {
+	var u uint64
+	return u, v.convertTo(&u)
+}
+
+// Float returns the value converted to float64.
+func (v Value) Float() (float64, error) {
+	var f float64
+	return f, v.convertTo(&f)
+}
+
+// Bool returns the value converted to bool. Strings are parsed with strconv.ParseBool.
+func (v Value) Bool() (bool, error) {
+	var b bool
+	return b, v.convertTo(&b)
+}
+
+// String returns the value converted to string. Numbers and booleans are formatted with strconv.
+// Note, that Value doesn't implement fmt.Stringer, as String also returns an error.
+func (v Value) String() (string, error) {
+	var s string
+	return s, v.convertTo(&s)
+}
+
+func (v Value) convertTo(ptr interface{}) error {
+	if !v.found {
+		return ErrNotFound
+	}
+	dst := reflect.ValueOf(ptr).Elem()
+	res, err := convert(v.v, dst.Type())
+	if err != nil {
+		var convErr *ConversionError
+		if errors.As(err, &convErr) {
+			convErr.Path = v.path
+		}
+		return err
+	}
+	dst.Set(res)
+	return nil
+}
@@ -0,0 +1,135 @@
+package goflat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/avdva/goflat/testpkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlatMapKeys(t *testing.T) {
+	a := assert.New(t)
+	m := Flatten(testpkg.NewTestStruct(), WithPointerFllowPolicy(PointerPolicyJustValue))
+	a.Equal([]string{
+		"A", "Array.0", "Array.1", "Array.2", "B", "Complex128", "Complex64", "Iface.Val", "M.key",
+		"Nested.Val", "PtrPtr", "S.D", "S.M.k", "S.Ptr", "Slice.0", "Slice.1", "Slice.2",
+	}, m.SortedKeys())
+	a.True(m.Has("S.M.k"))
+	a.False(m.Has("S.M"))
+	a.Equal([]string{"Array", "Iface", "M", "Nested", "S", "S.M", "Slice"}, m.Prefixes())
+	a.Equal(FlatMap{"D": "D", "Ptr": 123, "M.k": 123}, m.Sub("S"))
+	a.Equal(FlatMap{"k": 123}, m.Sub("S.M"))
+	a.Equal(FlatMap{"": 5}, m.Sub("A"))
+	a.Equal(FlatMap{}, m.Sub("S.M.k.x"))
+	a.Equal(m, m.Sub(""))
+}
+
+func TestFlatMapDelimeter(t *testing.T) {
+	a := assert.New(t)
+	m := Flatten(testpkg.NewTestStruct(), WithDelimeter("/"))
+	a.Equal(FlatMap{"k": 123}, m.Sub("S/M", WithDelimeter("/")))
+	a.Equal([]string{"Array", "Iface", "M", "Nested", "S", "S/M", "Slice"}, m.Prefixes(WithDelimeter("/")))
+}
+
+func TestFlatMapGet(t *testing.T) {
+	a := assert.New(t)
+	m := FlatMap{
+		"int":      5,
+		"uint":     uint8(7),
+		"float":    2.5,
+		"intFloat": float32(3),
+		"bool":     true,
+		"str":      "str",
+		"strInt":   "-12",
+		"strBool":  "true",
+		"strFloat": "1e3",
+		"negative": -1,
+	}
+	v := m.Get("none")
+	a.False(v.Exists())
+	_, err := v.Int()
+	a.True(errors.Is(err, ErrNotFound))
+
+	v = m.Get("int")
+	a.True(v.Exists())
+	a.Equal(5, v.Interface())
+	i, err := v.Int()
+	a.NoError(err)
+	a.Equal(int64(5), i)
+	f, err := v.Float()
+	a.NoError(err)
+	a.Equal(float64(5), f)
+	s, err := v.String()
+	a.NoError(err)
+	a.Equal("5", s)
+	_, err = v.Bool()
+	a.True(errors.Is(err, ErrConversion))
+
+	u, err := m.Get("uint").Uint()
+	a.NoError(err)
+	a.Equal(uint64(7), u)
+	_, err = m.Get("negative").Uint()
+	a.True(errors.Is(err, ErrConversion))
+
+	_, err = m.Get("float").Int()
+	var convErr *ConversionError
+	a.True(errors.As(err, &convErr))
+	a.Equal("float", convErr.Path)
+	i, err = m.Get("intFloat").Int()
+	a.NoError(err)
+	a.Equal(int64(3), i)
+
+	b, err := m.Get("bool").Bool()
+	a.NoError(err)
+	a.True(b)
+	b, err = m.Get("strBool").Bool()
+	a.NoError(err)
+	a.True(b)
+
+	i, err = m.Get("strInt").Int()
+	a.NoError(err)
+	a.Equal(int64(-12), i)
+	f, err = m.Get("strFloat").Float()
+	a.NoError(err)
+	a.Equal(float64(1000), f)
+	_, err = m.Get("str").Float()
+	a.True(errors.Is(err, ErrConversion))
+}
+
+func TestFlatMapGetPointer(t *testing.T) {
+	a := assert.New(t)
+	m := Flatten(testpkg.NewTestStruct())
+	i, err := m.Get("PtrPtr").Int()
+	a.NoError(err)
+	a.Equal(int64(123), i)
+}
+
+func TestFlatMapUnflatten(t *testing.T) {
+	a := assert.New(t)
+	m := FlatMap{
+		"A":       1,
+		"S.D":     "d",
+		"S.M.k":   2,
+		"Ptr":     "pointer",
+		"Ptr.Val": 3,
+	}
+	a.Equal(map[string]interface{}{
+		"A": 1,
+		"S": map[string]interface{}{
+			"D": "d",
+			"M": map[string]interface{}{
+				"k": 2,
+			},
+		},
+		"Ptr": map[string]interface{}{
+			"Val": 3,
+		},
+	}, m.Unflatten())
+	a.Equal(map[string]interface{}{
+		"S": map[string]interface{}{
+			"D": "d",
+		},
+	}, FlatMap{"S/D": "d"}.Unflatten(WithDelimeter("/")))
+}
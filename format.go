@@ -0,0 +1,216 @@
+package goflat
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BytesEncoding defines how byte slices and arrays are converted to strings.
+type BytesEncoding int8
+
+const (
+	// BytesBase64 encodes bytes with the standard base64 encoding. This is the default encoding.
+	BytesBase64 BytesEncoding = iota
+	// BytesHex encodes bytes as a lower-case hex string.
+	BytesHex
+	// BytesRaw converts bytes to a string as is.
+	BytesRaw
+)
+
+// QuotePolicy defines when formatted values are quoted with strconv.Quote.
+type QuotePolicy int8
+
+const (
+	// QuoteNever: values are never quoted. This is the default policy.
+	QuoteNever QuotePolicy = iota
+	// QuoteIfNeeded: values are quoted if they are empty, have leading or trailing spaces,
+	// or contain characters, which would be escaped by strconv.Quote.
+	QuoteIfNeeded
+	// QuoteAlways: all values are quoted.
+	QuoteAlways
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ValueFormatter converts values to strings.
+// Use NewValueFormatter to create a formatter with default settings.
+type ValueFormatter struct {
+	// FloatFormat is a format for strconv.FormatFloat. 'g' is the default.
+	FloatFormat byte
+	// FloatPrecision is a precision for strconv.FormatFloat.
+	// The default is -1, which means the smallest number of digits necessary to represent the value exactly.
+	FloatPrecision int
+	// TimeLayout is a layout for time.Time values. time.RFC3339Nano is the default.
+	TimeLayout string
+	// BytesEncoding defines how []byte and [N]byte values are encoded.
+	BytesEncoding BytesEncoding
+	// Quote defines when the formatted values are quoted.
+	Quote QuotePolicy
+
+	overrides map[reflect.Type]func(v interface{}) string
+}
+
+// NewValueFormatter returns a new formatter with default settings.
+func NewValueFormatter() *ValueFormatter {
+	return &ValueFormatter{
+		FloatFormat:    'g',
+		FloatPrecision: -1,
+		TimeLayout:     time.RFC3339Nano,
+	}
+}
+
+// Override sets a custom format function for values of type typ.
+// Values of such types are not expanded by FlattenStrings and are passed to fn as is.
+// The result of fn is still subject to the quoting policy.
+func (f *ValueFormatter) Override(typ reflect.Type, fn func(v interface{}) string) *ValueFormatter {
+	if f.overrides == nil {
+		f.overrides = make(map[reflect.Type]func(v interface{}) string)
+	}
+	f.overrides[typ] = fn
+	return f
+}
+
+// Format converts a value to a string. Nil values are converted to empty strings.
+func (f *ValueFormatter) Format(v interface{}) string {
+	return f.quote(f.format(reflect.ValueOf(v)))
+}
+
+func (f *ValueFormatter) format(val reflect.Value) string {
+	if !val.IsValid() {
+		return ""
+	}
+	if fn, found := f.overrides[val.Type()]; found && val.CanInterface() {
+		return fn(val.Interface())
+	}
+	if val.Type() == timeType && val.CanInterface() {
+		return val.Interface().(time.Time).Format(f.TimeLayout)
+	}
+	switch kind := val.Kind(); {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10)
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return strconv.FormatUint(val.Uint(), 10)
+	case kind == reflect.Float32:
+		return strconv.FormatFloat(val.Float(), f.FloatFormat, f.FloatPrecision, 32)
+	case kind == reflect.Float64:
+		return strconv.FormatFloat(val.Float(), f.FloatFormat, f.FloatPrecision, 64)
+	case kind == reflect.Complex64:
+		return strconv.FormatComplex(val.Complex(), f.FloatFormat, f.FloatPrecision, 64)
+	case kind == reflect.Complex128:
+		return strconv.FormatComplex(val.Complex(), f.FloatFormat, f.FloatPrecision, 128)
+	case kind == reflect.Bool:
+		return strconv.FormatBool(val.Bool())
+	case kind == reflect.String:
+		return val.String()
+	case kind == reflect.Pointer || kind == reflect.Interface:
+		if val.IsNil() {
+			return ""
+		}
+		return f.format(val.Elem())
+	case isBytes(val.Type()):
+		return f.formatBytes(val)
+	}
+	if val.CanInterface() {
+		return fmt.Sprint(val.Interface())
+	}
+	return ""
+}
+
+func (f *ValueFormatter) formatBytes(val reflect.Value) string {
+	b := make([]byte, val.Len())
+	for i := range b {
+		b[i] = byte(val.Index(i).Uint())
+	}
+	switch f.BytesEncoding {
+	case BytesHex:
+		return hex.EncodeToString(b)
+	case BytesRaw:
+		return string(b)
+	default:
+		return base64.StdEncoding.EncodeToString(b)
+	}
+}
+
+// parseBytes decodes s into a byte slice or array res, as formatBytes encodes it.
+func (f *ValueFormatter) parseBytes(res reflect.Value, s string) error {
+	var b []byte
+	var err error
+	switch f.BytesEncoding {
+	case BytesHex:
+		b, err = hex.DecodeString(s)
+	case BytesRaw:
+		b = []byte(s)
+	default:
+		b, err = base64.StdEncoding.DecodeString(s)
+	}
+	if err != nil {
+		return err
+	}
+	if res.Kind() == reflect.Slice {
+		res.Set(reflect.MakeSlice(res.Type(), len(b), len(b)))
+	} else if len(b) != res.Len() {
+		return fmt.Errorf("got %d bytes, expected %d", len(b), res.Len())
+	}
+	for i, c := range b {
+		res.Index(i).SetUint(uint64(c))
+	}
+	return nil
+}
+
+func (f *ValueFormatter) quote(s string) string {
+	switch f.Quote {
+	case QuoteAlways:
+		return strconv.Quote(s)
+	case QuoteIfNeeded:
+		if s == "" || strings.TrimSpace(s) != s {
+			return strconv.Quote(s)
+		}
+		if quoted := strconv.Quote(s); quoted[1:len(quoted)-1] != s {
+			return quoted
+		}
+	}
+	return s
+}
+
+// isLeaf returns true for the types, which are formatted as a whole.
+func (f *ValueFormatter) isLeaf(typ reflect.Type) bool {
+	if _, found := f.overrides[typ]; found {
+		return true
+	}
+	return typ == timeType
+}
+
+// WithValueFormatter option sets a formatter for FlattenStrings.
+func WithValueFormatter(f *ValueFormatter) Option {
+	return func(o *options) {
+		o.formatter = f
+	}
+}
+
+// FlattenStrings flattens a golang object and converts all the values to strings.
+// Values are formatted with the formatter set by WithValueFormatter option, or with the default one.
+// time.Time values and the types with overridden formatting are not expanded.
+func FlattenStrings(obj interface{}, opts ...Option) map[string]string {
+	m := make(map[string]string)
+	o := makeStringOptions(opts...)
+	if o.parallelism > 1 {
+		if kvs, ok := walkParallel(reflect.ValueOf(obj), o, func(v interface{}) interface{} { return o.formatter.Format(v) }); ok {
+			for _, kv := range kvs {
+				m[kv.Path] = kv.Value.(string)
+			}
+			return m
+		}
+	}
+	kb := keyBuilder{delim: o.delimeter}
+	w := newWalker(func(path []string, value interface{}) bool {
+		m[kb.key(path)] = o.formatter.Format(value)
+		return true
+	}, o)
+	w.run(reflect.ValueOf(obj))
+	return m
+}
@@ -0,0 +1,158 @@
+package goflat
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/avdva/goflat/testpkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlattenStrings(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(map[string]string{
+		"A":          "5",
+		"B":          "6",
+		"S.D":        "D",
+		"S.Ptr":      "123",
+		"S.M.k":      "123",
+		"Nested.Val": "true",
+		"Iface.Val":  "iface",
+		"PtrPtr":     "123",
+		"M.key":      "value",
+		"Slice.0":    "26.05",
+		"Slice.1":    "1.1",
+		"Slice.2":    "23.12",
+		"Array.0":    "1",
+		"Array.1":    "2",
+		"Array.2":    "3",
+		"Complex64":  "(1+2i)",
+		"Complex128": "(3+4i)",
+		"NilSlice":   "",
+	}, FlattenStrings(testpkg.NewTestStruct(), AddNilContainers(true)))
+}
+
+func TestFlattenStringsFormatter(t *testing.T) {
+	type custom struct {
+		A, B int
+	}
+	obj := struct {
+		Time     time.Time
+		TimePtr  *time.Time
+		Bytes    []byte
+		Array    [2]byte
+		Float    float64
+		Float32  float32
+		Duration time.Duration
+		Custom   custom
+		Str      string
+		Empty    string
+		Nil      *int
+	}{
+		Time:     time.Date(2023, 2, 3, 4, 5, 6, 7, time.UTC),
+		Bytes:    []byte("hello"),
+		Array:    [2]byte{0xab, 0xcd},
+		Float:    1.0 / 3,
+		Float32:  0.1,
+		Duration: time.Second,
+		Custom:   custom{A: 1, B: 2},
+		Str:      `say "hi"`,
+	}
+	obj.TimePtr = &obj.Time
+	tests := []struct {
+		f   *ValueFormatter
+		exp map[string]string
+	}{
+		{
+			f: NewValueFormatter(),
+			exp: map[string]string{
+				"Time":     "2023-02-03T04:05:06.000000007Z",
+				"TimePtr":  "2023-02-03T04:05:06.000000007Z",
+				"Bytes":    "aGVsbG8=",
+				"Array":    "q80=",
+				"Float":    "0.3333333333333333",
+				"Float32":  "0.1",
+				"Duration": "1000000000",
+				"Custom.A": "1",
+				"Custom.B": "2",
+				"Str":      `say "hi"`,
+				"Empty":    "",
+				"Nil":      "",
+			},
+		},
+		{
+			f: func() *ValueFormatter {
+				f := NewValueFormatter()
+				f.FloatFormat = 'f'
+				f.FloatPrecision = 2
+				f.TimeLayout = time.RFC3339
+				f.BytesEncoding = BytesHex
+				f.Quote = QuoteIfNeeded
+				f.Override(reflect.TypeOf(time.Duration(0)), func(v interface{}) string {
+					return v.(time.Duration).String()
+				})
+				f.Override(reflect.TypeOf(custom{}), func(v interface{}) string {
+					c := v.(custom)
+					return time.Duration(c.A + c.B).String()
+				})
+				return f
+			}(),
+			exp: map[string]string{
+				"Time":     "2023-02-03T04:05:06Z",
+				"TimePtr":  "2023-02-03T04:05:06Z",
+				"Bytes":    "68656c6c6f",
+				"Array":    "abcd",
+				"Float":    "0.33",
+				"Float32":  "0.10",
+				"Duration": "1s",
+				"Custom":   "3ns",
+				"Str":      `"say \"hi\""`,
+				"Empty":    `""`,
+				"Nil":      `""`,
+			},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.exp, FlattenStrings(obj, WithValueFormatter(test.f), AddNilFields(true)))
+	}
+}
+
+func TestFlattenStringsRoundTrip(t *testing.T) {
+	a := assert.New(t)
+	obj := struct {
+		Time  time.Time
+		Float float64
+		Int   int16
+		Bool  bool
+	}{
+		Time:  time.Date(2023, 2, 3, 4, 5, 6, 7, time.UTC),
+		Float: 1.0 / 3,
+		Int:   -5,
+		Bool:  true,
+	}
+	for k, v := range FlattenStrings(obj) {
+		val, err := convert(v, reflect.ValueOf(obj).FieldByName(k).Type())
+		a.NoError(err)
+		a.Equal(reflect.ValueOf(obj).FieldByName(k).Interface(), val.Interface())
+	}
+}
+
+func TestValueFormatter(t *testing.T) {
+	a := assert.New(t)
+	f := NewValueFormatter()
+	f.BytesEncoding = BytesRaw
+	a.Equal("hello", f.Format([]byte("hello")))
+	f.Quote = QuoteAlways
+	a.Equal(`"5"`, f.Format(5))
+	a.Equal(`"a b"`, f.Format("a b"))
+	f.Quote = QuoteIfNeeded
+	a.Equal(`5`, f.Format(5))
+	a.Equal(`a b`, f.Format("a b"))
+	a.Equal(`" a"`, f.Format(" a"))
+	a.Equal(`"a\nb"`, f.Format("a\nb"))
+	f.Quote = QuoteNever
+	a.Equal("a\nb", f.Format("a\nb"))
+	a.Equal("", f.Format(nil))
+}
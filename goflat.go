@@ -1,49 +1,122 @@
 package goflat
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/avdva/goflat/internal/tag"
 )
 
 type walker struct {
-	cb      WalkFunc
+	cb WalkFunc
+	// rcb is set instead of cb by WalkReflect.
+	rcb ReflectWalkFunc
+	// ccb, if set, is called for containers before visiting their elements.
+	ccb     WalkFunc
 	visited map[uintptr]struct{}
 	o       *options
+	// path is a reusable path buffer.
+	path []string
+	// ptrs is a stack of the pointers added to visited by visitPointer.
+	ptrs []uintptr
+	// genPath is the path of the struct, which is being visited by a generated WalkGoflat method.
+	genPath []string
+	// keyErr is the first error returned by keySegments, see CheckKeys.
+	keyErr error
+}
+
+// walkers is a pool of walkers with their path buffers and visited maps.
+var walkers = sync.Pool{
+	New: func() interface{} {
+		return &walker{
+			visited: make(map[uintptr]struct{}),
+			path:    make([]string, 0, 16),
+		}
+	},
 }
 
 func newWalker(cb WalkFunc, o *options) *walker {
-	return &walker{
-		cb:      cb,
-		visited: make(map[uintptr]struct{}),
-		o:       o,
-	}
+	w := walkers.Get().(*walker)
+	w.cb, w.o = cb, o
+	return w
 }
 
+// run walks the value and returns the walker to the pool. The walker can't be used after that.
 func (w *walker) run(val reflect.Value) {
-	path := make([]string, 0, 16)
-	w.visit(val, path)
+	w.visit(val, w.path[:0])
+	w.release()
+}
+
+// runAt is like run, but walks an element of a container. name is the path of the element,
+// visited are the pointers to be treated as already visited, i.e. the container and the pointers to it.
+// It returns false, if the walk of the container would be stopped after the element.
+func (w *walker) runAt(val reflect.Value, name string, visited []uintptr) (cont bool) {
+	for _, ptr := range visited {
+		w.visited[ptr] = struct{}{}
+	}
+	cont = w.visit(val, append(w.path[:0], name))
+	for _, ptr := range visited {
+		delete(w.visited, ptr)
+	}
+	w.release()
+	return cont
+}
+
+func (w *walker) release() {
+	// visited is usually empty at this point, as every container is removed from it after visiting.
+	// Generated code doesn't remove the containers, if the walk is stopped.
+	for ptr := range w.visited {
+		delete(w.visited, ptr)
+	}
+	path := w.path[:cap(w.path)]
+	for i := range path {
+		path[i] = ""
+	}
+	w.cb, w.rcb, w.ccb, w.o, w.keyErr, w.genPath = nil, nil, nil, nil, nil, nil
+	walkers.Put(w)
+}
+
+// visitContainer calls the container callback, if it is set, for a struct, a map, a slice or an array,
+// which is going to be expanded. The root value is not reported.
+func (w *walker) visitContainer(val reflect.Value, path []string) bool {
+	if w.ccb == nil || len(path) == 0 {
+		return true
+	}
+	return w.ccb(path, valueInterface(val))
+}
+
+// emit calls the callback for a value.
+func (w *walker) emit(path []string, value interface{}) bool {
+	if w.rcb != nil {
+		return w.rcb(path, reflect.ValueOf(value))
+	}
+	return w.cb(path, value)
+}
+
+// visitScalar calls the callback for a value of a scalar type. WalkReflect callbacks get the value as is.
+func (w *walker) visitScalar(visit visitFunc, val reflect.Value, path []string) bool {
+	if w.rcb != nil {
+		return w.rcb(path, val)
+	}
+	return visit(w, val, path)
 }
 
 func (w *walker) visit(val reflect.Value, path []string) (cont bool) {
+	if w.o.isLeaf != nil && val.IsValid() && w.o.isLeaf(val.Type()) {
+		return w.visitLeaf(val, path)
+	}
+	if !w.o.expandBytes && val.IsValid() && isBytes(val.Type()) {
+		return w.visitBytes(val, path)
+	}
+	if visitScalar := scalarVisitors[val.Kind()]; visitScalar != nil {
+		return w.visitScalar(visitScalar, val, path)
+	}
 	switch kind := val.Kind(); {
-	case kind >= reflect.Int && kind <= reflect.Int64:
-		cont = w.visitInt(val, path)
-	case kind >= reflect.Uint8 && kind <= reflect.Uint64:
-		cont = w.visitUint(val, path)
-	case kind == reflect.Float32:
-		cont = w.visitPrimitive(float32(val.Float()), path)
-	case kind == reflect.Float64:
-		cont = w.visitPrimitive(val.Float(), path)
-	case kind == reflect.Bool:
-		cont = w.visitPrimitive(val.Bool(), path)
-	case kind == reflect.Complex64:
-		cont = w.visitPrimitive(complex64(val.Complex()), path)
-	case kind == reflect.Complex128:
-		cont = w.visitPrimitive(val.Complex(), path)
-	case kind == reflect.String:
-		cont = w.visitPrimitive(val.String(), path)
 	case kind == reflect.Interface:
 		cont = w.visit(val.Elem(), path)
 	case kind == reflect.Pointer:
@@ -53,11 +126,47 @@ func (w *walker) visit(val reflect.Value, path []string) (cont bool) {
 	case kind == reflect.Map:
 		cont = w.visitMap(val, path)
 	case kind == reflect.Slice || kind == reflect.Array:
-		cont = w.visitSliceOrArray(val, path)
+		cont = w.visitSliceOrArray(val, path, w.o.sliceKeys[val.Type().Elem()])
 	}
 	return cont
 }
 
+// visitFunc visits a value of a particular kind.
+type visitFunc func(w *walker, val reflect.Value, path []string) (cont bool)
+
+// scalarVisitors are visit functions for scalar kinds, indexed by reflect.Kind.
+var scalarVisitors = [...]visitFunc{
+	reflect.Int:    (*walker).visitInt,
+	reflect.Int8:   (*walker).visitInt,
+	reflect.Int16:  (*walker).visitInt,
+	reflect.Int32:  (*walker).visitInt,
+	reflect.Int64:  (*walker).visitInt,
+	reflect.Uint8:  (*walker).visitUint,
+	reflect.Uint16: (*walker).visitUint,
+	reflect.Uint32: (*walker).visitUint,
+	reflect.Uint64: (*walker).visitUint,
+	reflect.Float32: func(w *walker, val reflect.Value, path []string) bool {
+		return w.visitPrimitive(float32(val.Float()), path)
+	},
+	reflect.Float64: func(w *walker, val reflect.Value, path []string) bool {
+		return w.visitPrimitive(val.Float(), path)
+	},
+	reflect.Bool: func(w *walker, val reflect.Value, path []string) bool {
+		return w.visitPrimitive(val.Bool(), path)
+	},
+	reflect.Complex64: func(w *walker, val reflect.Value, path []string) bool {
+		return w.visitPrimitive(complex64(val.Complex()), path)
+	},
+	reflect.Complex128: func(w *walker, val reflect.Value, path []string) bool {
+		return w.visitPrimitive(val.Complex(), path)
+	},
+	reflect.String: func(w *walker, val reflect.Value, path []string) bool {
+		return w.visitPrimitive(val.String(), path)
+	},
+	// the last kind makes the array cover all the kinds.
+	reflect.UnsafePointer: nil,
+}
+
 func (w *walker) visitInt(val reflect.Value, path []string) (cont bool) {
 	iVal := val.Int()
 	switch val.Kind() {
@@ -93,15 +202,57 @@ func (w *walker) visitUint(val reflect.Value, path []string) (cont bool) {
 }
 
 func (w *walker) visitPrimitive(val interface{}, path []string) (cont bool) {
-	return w.cb(path, val)
+	return w.emit(path, val)
+}
+
+// visitLeaf calls WalkFunc for a value of a non-primitive type, which should not be expanded.
+// Such values are skipped, if they can't be obtained, e.g. from unexported fields.
+func (w *walker) visitLeaf(val reflect.Value, path []string) (cont bool) {
+	if val.CanInterface() {
+		return w.emit(path, val.Interface())
+	}
+	if isPrimitive(val.Kind()) && val.Kind() != reflect.Pointer {
+		return w.emit(path, copyValue(val).Interface())
+	}
+	return true
+}
+
+// visitBytes calls WalkFunc for a byte slice or array as a whole.
+func (w *walker) visitBytes(val reflect.Value, path []string) (cont bool) {
+	if val.Kind() == reflect.Slice && val.IsNil() {
+		if w.o.addNilContainers {
+			return w.emit(path, nil)
+		}
+		return true
+	}
+	return w.emit(path, sliceInterface(val))
+}
+
+// sliceInterface returns val.Interface() for a slice or an array of primitives.
+// For values obtained from unexported fields it returns a copy.
+func sliceInterface(val reflect.Value) interface{} {
+	if val.CanInterface() {
+		return val.Interface()
+	}
+	var cp reflect.Value
+	if val.Kind() == reflect.Slice {
+		cp = reflect.MakeSlice(val.Type(), val.Len(), val.Len())
+	} else {
+		cp = reflect.New(val.Type()).Elem()
+	}
+	for i := 0; i < val.Len(); i++ {
+		cp.Index(i).Set(copyValue(val.Index(i)))
+	}
+	return cp.Interface()
 }
 
 func (w *walker) visitPointer(val reflect.Value, path []string) (cont bool) {
-	var addedPtrs []uintptr
+	start := len(w.ptrs)
 	defer func() {
-		for _, ptr := range addedPtrs {
+		for _, ptr := range w.ptrs[start:] {
 			delete(w.visited, ptr)
 		}
+		w.ptrs = w.ptrs[:start]
 	}()
 	var isNil bool
 	elem := val
@@ -113,25 +264,25 @@ func (w *walker) visitPointer(val reflect.Value, path []string) (cont bool) {
 			isNil = true
 		}
 		w.visited[elem.Pointer()] = struct{}{}
-		addedPtrs = append(addedPtrs, elem.Pointer())
+		w.ptrs = append(w.ptrs, elem.Pointer())
 	}
 	if isNil {
 		if val.CanInterface() {
-			return w.cb(path, val.Interface())
+			return w.emit(path, val.Interface())
 		}
-		return w.cb(path, reflect.New(val.Type()).Elem().Interface())
+		return w.emit(path, reflect.New(val.Type()).Elem().Interface())
 	}
 	switch w.o.pointerFollowPolicy {
 	case PointerPolicyJustPointer:
 		if val.CanInterface() {
-			if !w.cb(path, val.Interface()) {
+			if !w.emit(path, val.Interface()) {
 				return
 			}
 		}
 	case PointerPolicyPrimitivePointer:
 		if isPrimitive(elem.Kind()) {
 			if val.CanInterface() {
-				if !w.cb(path, val.Interface()) {
+				if !w.emit(path, val.Interface()) {
 					return
 				}
 			}
@@ -140,7 +291,7 @@ func (w *walker) visitPointer(val reflect.Value, path []string) (cont bool) {
 		}
 	case PointerPolicyBoth:
 		if val.CanInterface() {
-			if !w.cb(path, val.Interface()) {
+			if !w.emit(path, val.Interface()) {
 				return
 			}
 		}
@@ -184,23 +335,90 @@ func isPrimitive(kind reflect.Kind) bool {
 	return primitives[int(kind)]
 }
 
+// isBytes returns true for []byte and [N]byte types.
+func isBytes(typ reflect.Type) bool {
+	kind := typ.Kind()
+	return (kind == reflect.Slice || kind == reflect.Array) && typ.Elem().Kind() == reflect.Uint8
+}
+
 func (w *walker) visitStruct(val reflect.Value, path []string) (cont bool) {
-	typ := val.Type()
+	if !w.visitContainer(val, path) {
+		return false
+	}
+	if val.CanInterface() && hasGenerated(val.Type()) {
+		return w.visitGenerated(val, path)
+	}
+	for _, field := range w.o.structFields(val.Type()) {
+		fieldVal, fieldPath := val.Field(field.index), append(path, field.name)
+		switch {
+		case field.visit != nil && w.o.isLeaf == nil:
+			cont = w.visitScalar(field.visit, fieldVal, fieldPath)
+		case field.key != "":
+			cont = w.visitSliceOrArray(fieldVal, fieldPath, field.key)
+		default:
+			cont = w.visit(fieldVal, fieldPath)
+		}
+		if !cont {
+			return false
+		}
+	}
+	return true
+}
+
+// structField describes a struct field to visit.
+type structField struct {
+	index int
+	name  string
+	// key is a key field name for slice and array fields, see SliceKeyField.
+	key string
+	// visit is a visit function for the fields of scalar types.
+	visit visitFunc
+}
+
+// structPlanKey identifies a cached list of struct fields. It includes the options, which affect the list.
+type structPlanKey struct {
+	typ              reflect.Type
+	expandUnexported bool
+}
+
+// structPlans caches the results of structFields.
+var structPlans sync.Map
+
+// structFields returns the fields of the struct type, which should be visited.
+// The result is cached and must not be modified.
+func (o *options) structFields(typ reflect.Type) []structField {
+	key := structPlanKey{typ: typ, expandUnexported: o.expandUnexported}
+	if fields, found := structPlans.Load(key); found {
+		return fields.([]structField)
+	}
+	fields, _ := structPlans.LoadOrStore(key, o.buildStructFields(typ))
+	return fields.([]structField)
+}
+
+func (o *options) buildStructFields(typ reflect.Type) []structField {
+	fields := make([]structField, 0, typ.NumField())
 	for i := 0; i < typ.NumField(); i++ {
 		tf := typ.Field(i)
-		if tf.IsExported() || w.o.expandUnexported {
-			if !w.visit(val.Field(i), append(path, typ.Field(i).Name)) {
-				return false
-			}
+		if !tf.IsExported() && !o.expandUnexported {
+			continue
+		}
+		ft := tag.Parse(tf.Name, tf.Tag)
+		if ft.Skip {
+			continue
 		}
+		field := structField{index: i, name: ft.Name, visit: scalarVisitors[tf.Type.Kind()]}
+		if kind := tf.Type.Kind(); (kind == reflect.Slice || kind == reflect.Array) && !isBytes(tf.Type) {
+			field.key = ft.Key
+		}
+		fields = append(fields, field)
 	}
-	return true
+	return fields
 }
 
 func (w *walker) visitMap(val reflect.Value, path []string) (cont bool) {
 	if val.IsNil() {
 		if w.o.addNilContainers {
-			return w.cb(path, nil)
+			return w.emit(path, nil)
 		}
 		return true
 	}
@@ -210,27 +428,115 @@ func (w *walker) visitMap(val reflect.Value, path []string) (cont bool) {
 	w.visited[val.Pointer()] = struct{}{}
 	defer delete(w.visited, val.Pointer())
 	typ := val.Type()
-	if typ.Key().Kind() == reflect.String {
-		keys := val.MapKeys()
-		if w.o.sortMapKeys {
-			sort.Slice(keys, func(i, j int) bool {
-				return keys[i].String() < keys[j].String()
-			})
-		}
-		for _, key := range keys {
-			if !w.visit(val.MapIndex(key), append(path, key.String())) {
-				return false
-			}
+	if w.o.setMode != SetNone && isSet(typ) {
+		return w.visitSet(val, path)
+	}
+	if typ.Key().Kind() != reflect.String {
+		return true
+	}
+	if !w.visitContainer(val, path) {
+		return false
+	}
+	if !w.o.sortMapKeys && val.CanInterface() {
+		return w.visitMapUnsorted(val, path)
+	}
+	keys := val.MapKeys()
+	if w.o.sortMapKeys {
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+	}
+	for _, key := range keys {
+		if !w.visit(val.MapIndex(key), append(path, key.String())) {
+			return false
+		}
+	}
+	return true
+}
+
+// visitMapUnsorted visits map entries in the iteration order. Unlike MapKeys and MapIndex,
+// it copies keys and values into the same variables, so visiting an entry doesn't allocate.
+func (w *walker) visitMapUnsorted(val reflect.Value, path []string) (cont bool) {
+	typ := val.Type()
+	key, elem := reflect.New(typ.Key()).Elem(), reflect.New(typ.Elem()).Elem()
+	iter := val.MapRange()
+	for iter.Next() {
+		key.SetIterKey(iter)
+		elem.SetIterValue(iter)
+		if !w.visit(elem, append(path, key.String())) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSet returns true for map[T]struct{} and map[T]bool types, where T is a primitive type.
+func isSet(typ reflect.Type) bool {
+	switch typ.Key().Kind() {
+	case reflect.Array, reflect.Chan, reflect.Func, reflect.Interface, reflect.Map,
+		reflect.Pointer, reflect.Slice, reflect.Struct, reflect.UnsafePointer:
+		return false
+	}
+	elem := typ.Elem()
+	return elem.Kind() == reflect.Bool || (elem.Kind() == reflect.Struct && elem.NumField() == 0)
+}
+
+func (w *walker) visitSet(val reflect.Value, path []string) (cont bool) {
+	members := setMembers(val)
+	if w.o.setMode == SetAsValue {
+		return w.emit(path, members.Interface())
+	}
+	if !w.visitContainer(val, path) {
+		return false
+	}
+	for i := 0; i < members.Len(); i++ {
+		if !w.visit(members.Index(i), append(path, strconv.Itoa(i))) {
+			return false
 		}
 	}
 	return true
 }
 
-func (w *walker) visitSliceOrArray(val reflect.Value, path []string) (cont bool) {
+// setMembers returns a sorted slice of set members.
+func setMembers(val reflect.Value) reflect.Value {
+	members := reflect.MakeSlice(reflect.SliceOf(val.Type().Key()), 0, val.Len())
+	iter := val.MapRange()
+	for iter.Next() {
+		if v := iter.Value(); v.Kind() != reflect.Bool || v.Bool() {
+			members = reflect.Append(members, copyValue(iter.Key()))
+		}
+	}
+	sort.Slice(members.Interface(), func(i, j int) bool {
+		return lessValue(members.Index(i), members.Index(j))
+	})
+	return members
+}
+
+// lessValue compares two values of the same primitive type.
+func lessValue(a, b reflect.Value) bool {
+	switch kind := a.Kind(); {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return a.Int() < b.Int()
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		return a.Float() < b.Float()
+	case kind == reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case kind == reflect.String:
+		return a.String() < b.String()
+	case kind == reflect.Complex64 || kind == reflect.Complex128:
+		ac, bc := a.Complex(), b.Complex()
+		return real(ac) < real(bc) || (real(ac) == real(bc) && imag(ac) < imag(bc))
+	}
+	return false
+}
+
+func (w *walker) visitSliceOrArray(val reflect.Value, path []string, key string) (cont bool) {
 	if val.Kind() == reflect.Slice {
 		if val.IsNil() {
 			if w.o.addNilContainers {
-				return w.cb(path, nil)
+				return w.emit(path, nil)
 			}
 			return true
 		}
@@ -240,14 +546,256 @@ func (w *walker) visitSliceOrArray(val reflect.Value, path []string) (cont bool)
 		w.visited[val.Pointer()] = struct{}{}
 		defer delete(w.visited, val.Pointer())
 	}
+	if w.o.shouldCollapse(val.Type(), path) {
+		return w.visitCollapsed(val, path)
+	}
+	if !w.visitContainer(val, path) {
+		return false
+	}
+	if key != "" {
+		segments, err := w.o.keySegments(val, path, key)
+		if err == nil {
+			return w.visitKeyed(val, path, segments)
+		}
+		if w.keyErr == nil {
+			w.keyErr = err
+		}
+	}
 	for i := 0; i < val.Len(); i++ {
-		if !w.visit(val.Index(i), append(path, strconv.Itoa(i))) {
+		if !w.visit(val.Index(i), append(path, w.o.indexSegment(i, key))) {
+			return false
+		}
+	}
+	return true
+}
+
+// IndexPrefix prefixes the indexes of slice elements with KeyStyleSegment, if the elements can't be addressed
+// by their key field, e.g. "Items.#0.Name". Key values starting with IndexPrefix are not used in such slices.
+const IndexPrefix = "#"
+
+// ErrKeyCollision is returned by CheckKeys, if the elements of a slice can't be addressed by their key field.
+var ErrKeyCollision = errors.New("goflat: key collision")
+
+// CheckKeys walks obj and returns an error wrapping ErrKeyCollision for the first slice or array, whose elements
+// can't be addressed by their key field: some key values repeat, contain the delimeter, '[', ']' or '=',
+// or some elements don't have the field.
+// Walk addresses the elements of such slices by index, see IndexPrefix.
+func CheckKeys(obj interface{}, opts ...Option) error {
+	w := newWalker(func([]string, interface{}) bool { return true }, makeOptions(opts...))
+	w.visit(reflect.ValueOf(obj), w.path[:0])
+	err := w.keyErr
+	w.release()
+	return err
+}
+
+// indexSegment returns a path element for the i-th element of a slice or an array.
+// key is a key field name, if the elements should have been addressed by it.
+func (o *options) indexSegment(i int, key string) string {
+	if key != "" && o.keyStyle == KeyStyleSegment {
+		return IndexPrefix + strconv.Itoa(i)
+	}
+	return strconv.Itoa(i)
+}
+
+// parseIndex parses a path element returned by indexSegment. It returns false, if name is a key value.
+func (o *options) parseIndex(name, key string) (int, bool) {
+	if key != "" && o.keyStyle == KeyStyleSegment {
+		if !strings.HasPrefix(name, IndexPrefix) {
+			return 0, false
+		}
+		name = name[len(IndexPrefix):]
+	}
+	idx, err := strconv.Atoi(name)
+	return idx, err == nil && idx >= 0
+}
+
+// reservedIn returns the first of the delimeter, '[', ']' and '=', which the key value contains, or "".
+// Such values can't be parsed back from a path.
+func (o *options) reservedIn(keyVal string) string {
+	for _, s := range [...]string{o.delimeter, "[", "]", "="} {
+		if strings.Contains(keyVal, s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// keySegments returns path elements for slice elements addressed by the key field.
+// It returns an error wrapping ErrKeyCollision, if any of the elements doesn't have the field,
+// if the key values repeat, or contain the delimeter or the brackets. In such case the elements are addressed by index.
+func (o *options) keySegments(val reflect.Value, path []string, key string) ([]string, error) {
+	f := o.formatter
+	if f == nil {
+		f = NewValueFormatter()
+	}
+	segments := make([]string, val.Len())
+	seen := make(map[string]int, val.Len())
+	for i := range segments {
+		field, ok := keyFieldValue(val.Index(i), key)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q: element %d has no field %s", ErrKeyCollision, strings.Join(path, o.delimeter), i, key)
+		}
+		keyVal := f.format(field)
+		if j, found := seen[keyVal]; found {
+			return nil, fmt.Errorf("%w: %q: elements %d and %d have %s=%s", ErrKeyCollision, strings.Join(path, o.delimeter), j, i, key, keyVal)
+		}
+		seen[keyVal] = i
+		if reserved := o.reservedIn(keyVal); reserved != "" {
+			return nil, fmt.Errorf("%w: %q: element %d has %s=%s containing %q", ErrKeyCollision, strings.Join(path, o.delimeter), i, key, keyVal, reserved)
+		}
+		switch {
+		case o.keyStyle == KeyStyleSegment:
+			if strings.HasPrefix(keyVal, IndexPrefix) {
+				return nil, fmt.Errorf("%w: %q: element %d has %s=%s starting with %q", ErrKeyCollision, strings.Join(path, o.delimeter), i, key, keyVal, IndexPrefix)
+			}
+			segments[i] = keyVal
+		case len(path) > 0:
+			segments[i] = path[len(path)-1] + "[" + key + "=" + keyVal + "]"
+		default:
+			segments[i] = "[" + key + "=" + keyVal + "]"
+		}
+	}
+	return segments, nil
+}
+
+// keyFieldValue returns the key field of a slice element, following pointers and interfaces.
+// It returns false, if there is no such field, or it is promoted through a nil embedded pointer.
+func keyFieldValue(elem reflect.Value, key string) (reflect.Value, bool) {
+	for elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Interface {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	sf, found := elem.Type().FieldByName(key)
+	if !found {
+		return reflect.Value{}, false
+	}
+	field, err := elem.FieldByIndexErr(sf.Index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	return field, true
+}
+
+func (w *walker) visitKeyed(val reflect.Value, path []string, segments []string) (cont bool) {
+	path = w.o.keyedParent(path)
+	for i, segment := range segments {
+		if !w.visit(val.Index(i), append(path, segment)) {
 			return false
 		}
 	}
 	return true
 }
 
+// keyedParent returns a path for the elements addressed by a key field.
+// For KeyStyleBracket the last path element is replaced with "name[key=value]".
+func (o *options) keyedParent(path []string) []string {
+	if o.keyStyle == KeyStyleBracket && len(path) > 0 {
+		return path[:len(path)-1]
+	}
+	return path
+}
+
+func (o *options) shouldCollapse(typ reflect.Type, path []string) bool {
+	if o.collapseMode == CollapseNone {
+		return false
+	}
+	switch typ.Elem().Kind() {
+	case reflect.Array, reflect.Chan, reflect.Func, reflect.Interface, reflect.Map,
+		reflect.Pointer, reflect.Slice, reflect.Struct, reflect.UnsafePointer:
+		return false
+	}
+	for _, pattern := range o.collapseExcluded {
+		if matchPath(pattern, path, o.delimeter) {
+			return false
+		}
+	}
+	if len(o.collapsePaths) == 0 {
+		return true
+	}
+	for _, pattern := range o.collapsePaths {
+		if matchPath(pattern, path, o.delimeter) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *walker) visitCollapsed(val reflect.Value, path []string) (cont bool) {
+	return w.emit(path, w.o.collapsedValue(val))
+}
+
+// collapsedValue returns a value for a slice or an array of primitives according to CollapseMode.
+func (o *options) collapsedValue(val reflect.Value) interface{} {
+	if o.collapseMode == CollapseKeep {
+		return sliceInterface(val)
+	}
+	f := o.formatter
+	if f == nil {
+		f = NewValueFormatter()
+	}
+	var sb strings.Builder
+	for i := 0; i < val.Len(); i++ {
+		if i > 0 {
+			sb.WriteString(o.collapseSep)
+		}
+		sb.WriteString(f.format(val.Index(i)))
+	}
+	return sb.String()
+}
+
+// matchPath checks if the path matches the pattern.
+// The pattern consists of elements separated by the delimeter, "*" matches any single element.
+func matchPath(pattern string, path []string, delim string) bool {
+	for _, elem := range path {
+		if pattern == "" {
+			return false
+		}
+		var patternElem string
+		patternElem, pattern, _ = strings.Cut(pattern, delim)
+		if patternElem != "*" && patternElem != elem {
+			return false
+		}
+	}
+	return pattern == ""
+}
+
+// CollapseMode defines how slices and arrays of primitive types are collapsed into single values.
+type CollapseMode int8
+
+const (
+	// CollapseNone: slices and arrays are expanded. This is the default mode.
+	CollapseNone CollapseMode = iota
+	// CollapseJoin: elements are formatted and joined with a separator into a string.
+	CollapseJoin
+	// CollapseKeep: slices and arrays are reported as is.
+	CollapseKeep
+)
+
+// SetMode defines how set-like maps, i.e. map[T]struct{} and map[T]bool, are visited.
+type SetMode int8
+
+const (
+	// SetNone: sets are visited as regular maps. This is the default mode.
+	SetNone SetMode = iota
+	// SetAsList: set members are visited as a sorted list, like "Set.0", "Set.1".
+	// For map[T]bool only the keys with true values are members.
+	SetAsList
+	// SetAsValue: a sorted []T of set members is reported as a single value.
+	SetAsValue
+)
+
+// KeyStyle defines how slice elements addressed by a key field are named.
+type KeyStyle int8
+
+const (
+	// KeyStyleBracket: elements are named like "Items[ID=42].Name". This is the default style.
+	KeyStyleBracket KeyStyle = iota
+	// KeyStyleSegment: elements are named like "Items.42.Name".
+	KeyStyleSegment
+)
+
 const (
 	// PointerPolicyBoth: WalkFunc will be called for both pointer (when possible) and underlying value.
 	PointerPolicyBoth = iota
@@ -269,7 +817,22 @@ type options struct {
 	addNilContainers    bool
 	addNilFields        bool
 	sortMapKeys         bool
+	expandBytes         bool
+	collapseMode        CollapseMode
+	collapseSep         string
+	collapsePaths       []string
+	collapseExcluded    []string
+	sliceKeys           map[reflect.Type]string
+	keyStyle            KeyStyle
+	setMode             SetMode
+	floatTolerance      float64
+	ignorePaths         []string
 	pointerFollowPolicy int8
+	formatter           *ValueFormatter
+	parallelism         int
+	flatSchema          bool
+	envSeparator        string
+	isLeaf              func(typ reflect.Type) bool
 }
 
 func makeOptions(opts ...Option) *options {
@@ -283,6 +846,17 @@ func makeOptions(opts ...Option) *options {
 	return options
 }
 
+// makeStringOptions is like makeOptions, but also sets up the formatter, as FlattenStrings uses it:
+// the default one, if not set by WithValueFormatter option, with the types it formats as a whole being leaves.
+func makeStringOptions(opts ...Option) *options {
+	o := makeOptions(opts...)
+	if o.formatter == nil {
+		o.formatter = NewValueFormatter()
+	}
+	o.isLeaf = o.formatter.isLeaf
+	return o
+}
+
 // Option allows to customise flattening
 type Option func(o *options)
 
@@ -322,6 +896,65 @@ func SortMapKeys(sort bool) Option {
 	}
 }
 
+// ExpandBytes option controls how to deal with byte slices and arrays.
+// By default, they are treated as a single value. If set, every byte is visited separately.
+func ExpandBytes(expand bool) Option {
+	return func(o *options) {
+		o.expandBytes = expand
+	}
+}
+
+// CollapsePrimitiveSlices option makes Walk report slices and arrays of primitive types
+// (numbers, booleans, strings) as single values. See CollapseMode consts.
+// For CollapseJoin mode, elements are formatted with the formatter set by WithValueFormatter option, or with the default one,
+// and joined with sep.
+// If paths are given, only slices and arrays with matching paths are collapsed. A path consists of elements
+// separated by the delimeter, "*" matches any single element, e.g. "Items.*.Tags".
+// See also CollapseExcludePaths.
+func CollapsePrimitiveSlices(mode CollapseMode, sep string, paths ...string) Option {
+	return func(o *options) {
+		o.collapseMode = mode
+		o.collapseSep = sep
+		o.collapsePaths = paths
+	}
+}
+
+// CollapseExcludePaths option excludes slices and arrays with matching paths from collapsing
+// by CollapsePrimitiveSlices option, even if they match its paths. Patterns are the same as for CollapsePrimitiveSlices.
+func CollapseExcludePaths(paths ...string) Option {
+	return func(o *options) {
+		o.collapseExcluded = paths
+	}
+}
+
+// SliceKeyField option makes Walk address elements of slices and arrays of typ (or *typ) by the value
+// of their field instead of index. The same can be set for a struct field with `goflat:",key=Field"` tag.
+// If the key values repeat within a slice, its elements are addressed by index.
+// See KeyStyle* consts for the naming of the elements.
+func SliceKeyField(typ reflect.Type, field string) Option {
+	return func(o *options) {
+		if o.sliceKeys == nil {
+			o.sliceKeys = make(map[reflect.Type]string)
+		}
+		o.sliceKeys[typ] = field
+		o.sliceKeys[reflect.PointerTo(typ)] = field
+	}
+}
+
+// WithKeyStyle option sets naming of slice elements addressed by a key field. See KeyStyle* consts.
+func WithKeyStyle(style KeyStyle) Option {
+	return func(o *options) {
+		o.keyStyle = style
+	}
+}
+
+// WithSetMode option sets how to visit set-like maps. See SetMode consts.
+func WithSetMode(mode SetMode) Option {
+	return func(o *options) {
+		o.setMode = mode
+	}
+}
+
 // WithPointerFllowPolicy option specifies how to handle pointer types. See PointerPolicy* consts.
 func WithPointerFllowPolicy(policy int8) Option {
 	return func(o *options) {
@@ -331,22 +964,132 @@ func WithPointerFllowPolicy(policy int8) Option {
 
 // Flatten flattens a golang object.
 // It expands structs, maps, slices and arrays, uses '.' as a default field delimeter.
-func Flatten(obj interface{}, opts ...Option) map[string]interface{} {
-	m := make(map[string]interface{})
+func Flatten(obj interface{}, opts ...Option) FlatMap {
+	return flatten(reflect.ValueOf(obj), makeOptions(opts...))
+}
+
+// FlattenOf is like Flatten, but checks the type of obj at compile time.
+// obj is passed to reflection by pointer, so it is not converted to interface{}.
+func FlattenOf[T any](obj T, opts ...Option) FlatMap {
+	return flatten(valueOf(&obj), makeOptions(opts...))
+}
+
+// valueOf returns the value, which ptr points to. Interface values are unwrapped, as reflect.ValueOf does.
+func valueOf[T any](ptr *T) reflect.Value {
+	val := reflect.ValueOf(ptr).Elem()
+	if val.Kind() == reflect.Interface {
+		return val.Elem()
+	}
+	return val
+}
+
+func flatten(val reflect.Value, o *options) FlatMap {
+	if o.parallelism > 1 {
+		if kvs, ok := walkParallel(val, o, nil); ok {
+			m := make(FlatMap, len(kvs))
+			for _, kv := range kvs {
+				m[kv.Path] = kv.Value
+			}
+			return m
+		}
+	}
+	m := make(FlatMap)
+	kb := keyBuilder{delim: o.delimeter}
+	w := newWalker(func(path []string, value interface{}) bool {
+		m[kb.key(path)] = value
+		return true
+	}, o)
+	w.run(val)
+	return m
+}
+
+// KV is a path-value pair.
+type KV struct {
+	Path  string
+	Value interface{}
+}
+
+// FlattenOrdered flattens a golang object preserving the order in which Walk visits the values:
+// struct fields in declaration order, slices and arrays in index order, map keys are sorted if SortMapKeys option is set.
+func FlattenOrdered(obj interface{}, opts ...Option) []KV {
 	o := makeOptions(opts...)
+	if o.parallelism > 1 {
+		if kvs, ok := walkParallel(reflect.ValueOf(obj), o, nil); ok {
+			return kvs
+		}
+	}
+	var kvs []KV
+	kb := keyBuilder{delim: o.delimeter}
 	w := newWalker(func(path []string, value interface{}) bool {
-		m[strings.Join(path, o.delimeter)] = value
+		kvs = append(kvs, KV{Path: kb.key(path), Value: value})
 		return true
 	}, o)
 	w.run(reflect.ValueOf(obj))
-	return m
+	return kvs
 }
 
 // WalkFunc is a callback to be called for each value.
+// The path slice is reused between the calls, so it must be copied to be retained.
 type WalkFunc func(path []string, value interface{}) bool
 
 // Walk calls cb for every member field of the obj.
 func Walk(obj interface{}, cb WalkFunc, opts ...Option) {
+	WalkValue(reflect.ValueOf(obj), cb, opts...)
+}
+
+// WalkValue is like Walk, but starts from a reflect.Value, e.g. a value of an unexported field.
+// Values, which can't be obtained with Interface(), are reported as copies for primitive types and skipped otherwise.
+func WalkValue(val reflect.Value, cb WalkFunc, opts ...Option) {
 	w := newWalker(cb, makeOptions(opts...))
+	w.run(val)
+}
+
+// ReflectWalkFunc is a callback for WalkReflect.
+// The path slice and the values of map entries are reused between the calls, so they must be copied to be retained.
+type ReflectWalkFunc func(path []string, value reflect.Value) bool
+
+// WalkReflect is like Walk, but passes the values as reflect.Value. Values of scalar types (numbers, booleans, strings)
+// are passed as is, without converting them to interface{}, so walking them doesn't allocate.
+// Unlike Walk, it keeps their named types, e.g. time.Duration is not converted to int64.
+// Note, that Interface() can't be called for the values obtained from unexported fields.
+// Other values are passed as reflect.ValueOf of what Walk would pass.
+func WalkReflect(obj interface{}, cb ReflectWalkFunc, opts ...Option) {
+	w := newWalker(nil, makeOptions(opts...))
+	w.rcb = cb
 	w.run(reflect.ValueOf(obj))
 }
+
+// keyBuilder joins paths into keys. It reuses the joined parent path of the previous key,
+// as consecutive paths usually share the parent.
+type keyBuilder struct {
+	delim  string
+	parent []string
+	prefix string
+}
+
+func (kb *keyBuilder) key(path []string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	parent, last := path[:len(path)-1], path[len(path)-1]
+	if len(parent) == 0 {
+		return last
+	}
+	if !equalPaths(parent, kb.parent) {
+		kb.parent = append(kb.parent[:0], parent...)
+		kb.prefix = strings.Join(parent, kb.delim)
+	}
+	return kb.prefix + kb.delim + last
+}
+
+func equalPaths(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
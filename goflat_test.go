@@ -1,6 +1,8 @@
 package goflat
 
 import (
+	"errors"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -461,16 +463,19 @@ func TestWalkFlatten(t *testing.T) {
 			test := tests[idx]
 			a := assert.New(t)
 			var slice []pathValue
+			var kvs []KV
 			m := make(map[string]interface{})
 			Walk(test.obj, func(path []string, value interface{}) bool {
 				p := make([]string, len(path))
 				copy(p, path)
 				slice = append(slice, pathValue{path: p, value: value})
+				kvs = append(kvs, KV{Path: strings.Join(path, "."), Value: value})
 				m[strings.Join(path, ".")] = value
 				return true
 			}, test.opts...)
 			a.Equal(test.exp, slice)
-			a.Equal(m, Flatten(test.obj, test.opts...))
+			a.Equal(FlatMap(m), Flatten(test.obj, test.opts...))
+			a.Equal(kvs, FlattenOrdered(test.obj, test.opts...))
 		})
 	}
 }
@@ -499,7 +504,7 @@ func TestCyclicMap(t *testing.T) {
 		},
 	}
 	m["m"] = mm
-	a.Equal(map[string]interface{}{}, Flatten(m))
+	a.Equal(FlatMap{}, Flatten(m))
 }
 
 func TestSortMapKeys(t *testing.T) {
@@ -525,6 +530,420 @@ func TestSortMapKeys(t *testing.T) {
 	assert.Equal(t, exp, act)
 }
 
+func TestFlattenOrdered(t *testing.T) {
+	a := assert.New(t)
+	obj := struct {
+		B int
+		A map[string]int
+		C []string
+	}{
+		B: 1,
+		A: map[string]int{"z": 1, "y": 2, "x": 3},
+		C: []string{"c", "b", "a"},
+	}
+	a.Equal([]KV{
+		{Path: "B", Value: 1},
+		{Path: "A/x", Value: 3},
+		{Path: "A/y", Value: 2},
+		{Path: "A/z", Value: 1},
+		{Path: "C/0", Value: "c"},
+		{Path: "C/1", Value: "b"},
+		{Path: "C/2", Value: "a"},
+	}, FlattenOrdered(obj, SortMapKeys(true), WithDelimeter("/")))
+	a.Nil(FlattenOrdered(nil))
+}
+
+func TestFlattenOf(t *testing.T) {
+	a := assert.New(t)
+	st := testpkg.NewTestStruct()
+	a.Equal(Flatten(st), FlattenOf(st))
+	a.Equal(Flatten(*st), FlattenOf(*st))
+	a.Equal(Flatten(st, Parallelism(4)), FlattenOf(st, Parallelism(4)))
+	var iface interface{} = map[string]int{"a": 1}
+	a.Equal(FlatMap{"a": 1}, FlattenOf(iface))
+	a.Empty(FlattenOf[interface{}](nil))
+	var flatten func(interface{}, ...Option) FlatMap = Flatten
+	a.Equal(FlatMap{"a": 1}, flatten(iface))
+}
+
+func TestBytes(t *testing.T) {
+	a := assert.New(t)
+	type bytesStruct struct {
+		Slice      []byte
+		Array      [3]byte
+		Nil        []byte
+		unexported []byte
+	}
+	obj := bytesStruct{
+		Slice:      []byte{1, 2},
+		Array:      [3]byte{3, 4, 5},
+		unexported: []byte{6},
+	}
+	a.Equal(FlatMap{
+		"Slice":      []byte{1, 2},
+		"Array":      [3]byte{3, 4, 5},
+		"Nil":        nil,
+		"unexported": []byte{6},
+	}, Flatten(obj, ExpandUnexported(true), AddNilContainers(true)))
+	a.Equal(FlatMap{
+		"Slice.0":      uint8(1),
+		"Slice.1":      uint8(2),
+		"Array.0":      uint8(3),
+		"Array.1":      uint8(4),
+		"Array.2":      uint8(5),
+		"unexported.0": uint8(6),
+	}, Flatten(obj, ExpandUnexported(true), ExpandBytes(true)))
+}
+
+func TestCollapsePrimitiveSlices(t *testing.T) {
+	type item struct {
+		Tags []string
+	}
+	obj := struct {
+		Tags    []string
+		Ints    [3]int
+		Floats  []float64
+		Items   []item
+		Structs []struct{ A int }
+		Nil     []string
+	}{
+		Tags:    []string{"a", "b"},
+		Ints:    [3]int{1, 2, 3},
+		Floats:  []float64{0.5},
+		Items:   []item{{Tags: []string{"c"}}, {Tags: []string{"d", "e"}}},
+		Structs: []struct{ A int }{{A: 1}},
+	}
+	tests := []struct {
+		opts []Option
+		exp  FlatMap
+	}{
+		{
+			opts: []Option{CollapsePrimitiveSlices(CollapseJoin, ",")},
+			exp: FlatMap{
+				"Tags":         "a,b",
+				"Ints":         "1,2,3",
+				"Floats":       "0.5",
+				"Items.0.Tags": "c",
+				"Items.1.Tags": "d,e",
+				"Structs.0.A":  1,
+			},
+		},
+		{
+			opts: []Option{CollapsePrimitiveSlices(CollapseKeep, ""), AddNilContainers(true)},
+			exp: FlatMap{
+				"Tags":         []string{"a", "b"},
+				"Ints":         [3]int{1, 2, 3},
+				"Floats":       []float64{0.5},
+				"Items.0.Tags": []string{"c"},
+				"Items.1.Tags": []string{"d", "e"},
+				"Structs.0.A":  1,
+				"Nil":          nil,
+			},
+		},
+		{
+			opts: []Option{CollapsePrimitiveSlices(CollapseJoin, ";", "Items/*/Tags", "Ints"), WithDelimeter("/")},
+			exp: FlatMap{
+				"Tags/0":       "a",
+				"Tags/1":       "b",
+				"Ints":         "1;2;3",
+				"Floats/0":     0.5,
+				"Items/0/Tags": "c",
+				"Items/1/Tags": "d;e",
+				"Structs/0/A":  1,
+			},
+		},
+		{
+			opts: []Option{CollapsePrimitiveSlices(CollapseJoin, ","), CollapseExcludePaths("Items.*.Tags", "Floats")},
+			exp: FlatMap{
+				"Tags":           "a,b",
+				"Ints":           "1,2,3",
+				"Floats.0":       0.5,
+				"Items.0.Tags.0": "c",
+				"Items.1.Tags.0": "d",
+				"Items.1.Tags.1": "e",
+				"Structs.0.A":    1,
+			},
+		},
+		{
+			opts: []Option{CollapsePrimitiveSlices(CollapseJoin, ",", "Items.*.Tags"), CollapseExcludePaths("Items.1.Tags")},
+			exp: FlatMap{
+				"Tags.0":         "a",
+				"Tags.1":         "b",
+				"Ints.0":         1,
+				"Ints.1":         2,
+				"Ints.2":         3,
+				"Floats.0":       0.5,
+				"Items.0.Tags":   "c",
+				"Items.1.Tags.0": "d",
+				"Items.1.Tags.1": "e",
+				"Structs.0.A":    1,
+			},
+		},
+	}
+	for i, test := range tests {
+		assert.Equal(t, test.exp, Flatten(obj, test.opts...), "test %d", i)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	a := assert.New(t)
+	a.True(matchPath("", []string{}, "."))
+	a.True(matchPath("a.b", []string{"a", "b"}, "."))
+	a.True(matchPath("a.*.c", []string{"a", "b", "c"}, "."))
+	a.False(matchPath("a.*.c", []string{"a", "b"}, "."))
+	a.False(matchPath("a.b", []string{"a", "b", "c"}, "."))
+	a.False(matchPath("a.b", []string{"a", "c"}, "."))
+	a.False(matchPath("a", []string{}, "."))
+}
+
+type keyItem struct {
+	ID   int
+	Name string
+}
+
+func TestSliceKeyField(t *testing.T) {
+	type tagged struct {
+		Items   []keyItem `goflat:",key=ID"`
+		Renamed []keyItem `goflat:"renamed,key=Name"`
+		Dups    []keyItem `goflat:",key=Name"`
+		NoField []keyItem `goflat:",key=None"`
+		Ptrs    []*keyItem
+		Skipped int `goflat:"-"`
+	}
+	obj := tagged{
+		Items:   []keyItem{{ID: 42, Name: "a"}, {ID: 7, Name: "b"}},
+		Renamed: []keyItem{{ID: 1, Name: "x"}},
+		Dups:    []keyItem{{ID: 1, Name: "x"}, {ID: 2, Name: "x"}},
+		NoField: []keyItem{{ID: 1}},
+		Ptrs:    []*keyItem{{ID: 3, Name: "c"}},
+		Skipped: 1,
+	}
+	a := assert.New(t)
+	a.Equal(FlatMap{
+		"Items[ID=42].ID":      42,
+		"Items[ID=42].Name":    "a",
+		"Items[ID=7].ID":       7,
+		"Items[ID=7].Name":     "b",
+		"renamed[Name=x].ID":   1,
+		"renamed[Name=x].Name": "x",
+		"Dups.0.ID":            1,
+		"Dups.0.Name":          "x",
+		"Dups.1.ID":            2,
+		"Dups.1.Name":          "x",
+		"NoField.0.ID":         1,
+		"NoField.0.Name":       "",
+		"Ptrs[ID=3].ID":        3,
+		"Ptrs[ID=3].Name":      "c",
+	}, Flatten(obj, SliceKeyField(reflect.TypeOf(keyItem{}), "ID")))
+	a.Equal(FlatMap{
+		"Items.42.ID":   42,
+		"Items.42.Name": "a",
+		"Items.7.ID":    7,
+		"Items.7.Name":  "b",
+	}, Flatten(struct {
+		Items []keyItem `goflat:",key=ID"`
+	}{Items: obj.Items}, WithKeyStyle(KeyStyleSegment)))
+	a.Equal(FlatMap{
+		"[Name=a].ID":   42,
+		"[Name=a].Name": "a",
+		"[Name=b].ID":   7,
+		"[Name=b].Name": "b",
+	}, Flatten(obj.Items, SliceKeyField(reflect.TypeOf(keyItem{}), "Name")))
+}
+
+func TestKeyCollisions(t *testing.T) {
+	a := assert.New(t)
+	type embedded struct {
+		*keyItem
+	}
+	type tagged struct {
+		Dups   []keyItem  `goflat:",key=ID"`
+		Marked []keyItem  `goflat:",key=Name"`
+		Nil    []embedded `goflat:",key=ID"`
+	}
+	obj := tagged{
+		Dups:   []keyItem{{ID: 1, Name: "x"}, {ID: 1, Name: "y"}},
+		Marked: []keyItem{{ID: 2, Name: "#1"}},
+		Nil:    []embedded{{}},
+	}
+	m := Flatten(obj, WithKeyStyle(KeyStyleSegment))
+	a.Equal(FlatMap{
+		"Dups.#0.ID":     1,
+		"Dups.#0.Name":   "x",
+		"Dups.#1.ID":     1,
+		"Dups.#1.Name":   "y",
+		"Marked.#0.ID":   2,
+		"Marked.#0.Name": "#1",
+	}, m)
+	v, err := Get(obj, "Dups.#1.Name", WithKeyStyle(KeyStyleSegment))
+	a.NoError(err)
+	a.Equal("y", v)
+	var dst tagged
+	a.NoError(Apply(&dst, map[string]interface{}{"Dups.#0.ID": 1, "Dups.#1.ID": 1}, WithKeyStyle(KeyStyleSegment)))
+	a.Equal([]keyItem{{ID: 1}, {ID: 1}}, dst.Dups)
+	sel, err := Compile("Dups.*.Name", WithKeyStyle(KeyStyleSegment))
+	a.NoError(err)
+	a.Equal([]KV{{Path: "Dups.#0.Name", Value: "x"}, {Path: "Dups.#1.Name", Value: "y"}}, sel.SelectAll(obj))
+	sel, err = Compile("Dups.#1.Name", WithKeyStyle(KeyStyleSegment))
+	a.NoError(err)
+	a.Equal([]KV{{Path: "Dups.#1.Name", Value: "y"}}, sel.SelectAll(obj))
+
+	err = CheckKeys(obj)
+	a.True(errors.Is(err, ErrKeyCollision))
+	a.EqualError(err, `goflat: key collision: "Dups": elements 0 and 1 have ID=1`)
+	err = CheckKeys(tagged{Marked: obj.Marked}, WithKeyStyle(KeyStyleSegment))
+	a.EqualError(err, `goflat: key collision: "Marked": element 0 has Name=#1 starting with "#"`)
+	a.NoError(CheckKeys(tagged{Marked: obj.Marked}))
+	err = CheckKeys(tagged{Nil: obj.Nil})
+	a.EqualError(err, `goflat: key collision: "Nil": element 0 has no field ID`)
+}
+
+func TestReservedKeyValues(t *testing.T) {
+	a := assert.New(t)
+	type server struct {
+		Host   string
+		Weight float64
+	}
+	type config struct {
+		Servers []server `goflat:",key=Host"`
+		Weights []server `goflat:",key=Weight"`
+	}
+	obj := config{
+		Servers: []server{{Host: "db.example.com", Weight: 1}, {Host: "h1", Weight: 2}},
+		Weights: []server{{Host: "h2", Weight: 1.5}},
+	}
+	for _, style := range []KeyStyle{KeyStyleBracket, KeyStyleSegment} {
+		prefix := ""
+		if style == KeyStyleSegment {
+			prefix = IndexPrefix
+		}
+		m := Flatten(obj, WithKeyStyle(style))
+		a.Equal(FlatMap{
+			"Servers." + prefix + "0.Host":   "db.example.com",
+			"Servers." + prefix + "0.Weight": 1.0,
+			"Servers." + prefix + "1.Host":   "h1",
+			"Servers." + prefix + "1.Weight": 2.0,
+			"Weights." + prefix + "0.Host":   "h2",
+			"Weights." + prefix + "0.Weight": 1.5,
+		}, m)
+		for path, value := range m {
+			got, err := Get(obj, path, WithKeyStyle(style))
+			a.NoError(err, path)
+			a.Equal(value, got, path)
+		}
+		var dst config
+		a.NoError(Apply(&dst, m, WithKeyStyle(style)))
+		a.Equal(obj, dst)
+		sel, err := Compile("Servers.*.Host", WithKeyStyle(style))
+		a.NoError(err)
+		a.Equal([]KV{
+			{Path: "Servers." + prefix + "0.Host", Value: "db.example.com"},
+			{Path: "Servers." + prefix + "1.Host", Value: "h1"},
+		}, sel.SelectAll(obj))
+	}
+	err := CheckKeys(obj)
+	a.EqualError(err, `goflat: key collision: "Servers": element 0 has Host=db.example.com containing "."`)
+	a.NoError(CheckKeys(obj, WithDelimeter("/")))
+	a.Equal(FlatMap{
+		"Servers[Host=db.example.com]/Host":   "db.example.com",
+		"Servers[Host=db.example.com]/Weight": 1.0,
+		"Servers[Host=h1]/Host":               "h1",
+		"Servers[Host=h1]/Weight":             2.0,
+		"Weights[Weight=1.5]/Host":            "h2",
+		"Weights[Weight=1.5]/Weight":          1.5,
+	}, Flatten(obj, WithDelimeter("/")))
+	for _, key := range []string{"a[b", "a]", "a=b"} {
+		err := CheckKeys(config{Servers: []server{{Host: key}}})
+		a.True(errors.Is(err, ErrKeyCollision), key)
+	}
+}
+
+func TestSetMode(t *testing.T) {
+	a := assert.New(t)
+	obj := struct {
+		Strings map[string]struct{}
+		Ints    map[int]bool
+		NotSet  map[string]int
+	}{
+		Strings: map[string]struct{}{"c": {}, "a": {}, "b": {}},
+		Ints:    map[int]bool{3: true, -1: true, 2: false, 10: true},
+		NotSet:  map[string]int{"k": 1},
+	}
+	a.Equal(FlatMap{"NotSet.k": 1}, Flatten(obj))
+	a.Equal([]KV{
+		{Path: "Strings.0", Value: "a"},
+		{Path: "Strings.1", Value: "b"},
+		{Path: "Strings.2", Value: "c"},
+		{Path: "Ints.0", Value: -1},
+		{Path: "Ints.1", Value: 3},
+		{Path: "Ints.2", Value: 10},
+		{Path: "NotSet.k", Value: 1},
+	}, FlattenOrdered(obj, WithSetMode(SetAsList)))
+	a.Equal(FlatMap{
+		"Strings":  []string{"a", "b", "c"},
+		"Ints":     []int{-1, 3, 10},
+		"NotSet.k": 1,
+	}, Flatten(obj, WithSetMode(SetAsValue)))
+}
+
+func TestWalkReflect(t *testing.T) {
+	a := assert.New(t)
+	for _, opts := range [][]Option{nil, {ExpandUnexported(true), AddNilContainers(true)}, {SortMapKeys(true)}} {
+		exp := Flatten(testpkg.NewTestStruct(), opts...)
+		got := FlatMap{}
+		WalkReflect(testpkg.NewTestStruct(), func(path []string, value reflect.Value) bool {
+			got[strings.Join(path, ".")] = valueInterface(value)
+			return true
+		}, opts...)
+		a.Equal(exp, got)
+	}
+	type named int
+	WalkReflect(struct{ N named }{N: 5}, func(path []string, value reflect.Value) bool {
+		a.Equal(named(5), value.Interface())
+		return true
+	})
+}
+
+func TestWalkValue(t *testing.T) {
+	a := assert.New(t)
+	st := reflect.ValueOf(testpkg.NewTestStruct()).Elem()
+	got := FlatMap{}
+	WalkValue(st.FieldByName("S"), func(path []string, value interface{}) bool {
+		got[strings.Join(path, ".")] = value
+		return true
+	}, ExpandUnexported(true))
+	a.Equal(FlatMap{"D": "D", "Ptr": st.FieldByName("S").Field(1).Interface(), "M.k": 123, "notExportedMap.k": "v"}, got)
+	got = FlatMap{}
+	WalkValue(st.FieldByName("notExportedStruct"), func(path []string, value interface{}) bool {
+		got[strings.Join(path, ".")] = value
+		return true
+	})
+	a.Equal(FlatMap{"A": 0}, got)
+}
+
+func TestWalkReflectAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not stable with the race detector")
+	}
+	type obj struct {
+		Ints    []int
+		Strings []string
+		Floats  map[string]float64
+	}
+	small := obj{Ints: []int{1}, Strings: []string{"a"}, Floats: map[string]float64{"a": 1}}
+	large := obj{Ints: make([]int, 100), Strings: make([]string, 100), Floats: make(map[string]float64)}
+	for i := 0; i < 100; i++ {
+		large.Floats[strconv.Itoa(i)] = float64(i)
+	}
+	cb := func([]string, reflect.Value) bool { return true }
+	allocs := func(o *obj) float64 {
+		return testing.AllocsPerRun(100, func() {
+			WalkReflect(o, cb)
+		})
+	}
+	assert.Equal(t, allocs(&small), allocs(&large))
+}
+
 func TestWalkStop(t *testing.T) {
 	st := testpkg.NewTestStruct()
 	var total int
@@ -542,3 +961,103 @@ func TestWalkStop(t *testing.T) {
 		assert.Equal(t, i+1, current)
 	}
 }
+
+func TestStructPlans(t *testing.T) {
+	a := assert.New(t)
+	type plan struct {
+		A       int
+		private string
+	}
+	obj := plan{A: 1, private: "p"}
+	for i := 0; i < 2; i++ {
+		a.Equal(FlatMap{"A": 1}, Flatten(obj))
+		a.Equal(FlatMap{"A": 1, "private": "p"}, Flatten(obj, ExpandUnexported(true)))
+	}
+	a.Equal(map[string]string{"A": "1", "private": "p"}, FlattenStrings(obj, ExpandUnexported(true)))
+}
+
+type benchStruct struct {
+	ID      int64
+	Name    string
+	Enabled bool
+	Ratio   float64
+	Tags    []string
+	Labels  map[string]string
+	Owner   *benchOwner
+	Items   []benchItem
+}
+
+type benchOwner struct {
+	Name  string `goflat:"name"`
+	Email string `goflat:"email"`
+}
+
+type benchItem struct {
+	SKU   string
+	Price float64
+	Qty   int32
+}
+
+func newBenchStruct() *benchStruct {
+	return &benchStruct{
+		ID:      42,
+		Name:    "bench",
+		Enabled: true,
+		Ratio:   0.5,
+		Tags:    []string{"a", "b", "c"},
+		Labels:  map[string]string{"env": "prod", "team": "core"},
+		Owner:   &benchOwner{Name: "owner", Email: "owner@example.com"},
+		Items: []benchItem{
+			{SKU: "x", Price: 1.5, Qty: 1},
+			{SKU: "y", Price: 2.5, Qty: 2},
+			{SKU: "z", Price: 3.5, Qty: 3},
+		},
+	}
+}
+
+func BenchmarkWalk(b *testing.B) {
+	obj := newBenchStruct()
+	cb := func([]string, interface{}) bool { return true }
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Walk(obj, cb)
+	}
+}
+
+func BenchmarkFlatten(b *testing.B) {
+	obj := newBenchStruct()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Flatten(obj)
+	}
+}
+
+func BenchmarkWalkReflect(b *testing.B) {
+	obj := newBenchStruct()
+	cb := func([]string, reflect.Value) bool { return true }
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		WalkReflect(obj, cb)
+	}
+}
+
+// BenchmarkStructPlans compares flattening with the cached struct plans and with the plans rebuilt for each call.
+func BenchmarkStructPlans(b *testing.B) {
+	obj := newBenchStruct()
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Flatten(obj)
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			structPlans.Range(func(key, _ interface{}) bool {
+				structPlans.Delete(key)
+				return true
+			})
+			Flatten(obj)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// Package source loads and type-checks Go packages for goflat commands.
+package source
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Package is a parsed and type-checked package.
+type Package struct {
+	Fset *token.FileSet
+	Name string
+	// Files are sorted by file name.
+	Files []*ast.File
+	Types *types.Package
+	// Info has the definitions of the identifiers.
+	Info *types.Info
+}
+
+// Load parses the package in dir, except for test files and the files, for which skip returns true.
+// The types, which can't be resolved, are left invalid, so the package doesn't have to compile.
+func Load(dir string, skip func(name string) bool) (*Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && (skip == nil || !skip(fi.Name()))
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+	var astPkg *ast.Package
+	for _, p := range pkgs {
+		astPkg = p
+	}
+	fileNames := make([]string, 0, len(astPkg.Files))
+	for name := range astPkg.Files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+	files := make([]*ast.File, 0, len(fileNames))
+	for _, name := range fileNames {
+		files = append(files, astPkg.Files[name])
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	pkg, _ := conf.Check(astPkg.Name, fset, files, info)
+	return &Package{Fset: fset, Name: astPkg.Name, Files: files, Types: pkg, Info: info}, nil
+}
+
+// IsBytes returns true for []byte and [N]byte types.
+func IsBytes(typ types.Type) bool {
+	elem := ListElem(typ)
+	if elem == nil {
+		return false
+	}
+	basic, ok := elem.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.Uint8
+}
+
+// IsContainer returns true for slices and arrays, which are not byte slices.
+func IsContainer(typ types.Type) bool {
+	return ListElem(typ) != nil && !IsBytes(typ)
+}
+
+// ListElem returns the element type of a slice or an array, or nil for other types.
+func ListElem(typ types.Type) types.Type {
+	switch t := typ.Underlying().(type) {
+	case *types.Slice:
+		return t.Elem()
+	case *types.Array:
+		return t.Elem()
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Package tag parses goflat struct tags for the goflat package and its commands.
+package tag
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Tag is a parsed `goflat:"name,key=Field"` struct tag.
+type Tag struct {
+	// Name is the name of the field in the paths, the Go name by default.
+	Name string
+	// Key is a key field name for slices and arrays, see goflat.SliceKeyField.
+	Key string
+	// Skip is set by `goflat:"-"` tag.
+	Skip bool
+}
+
+// Parse parses the goflat tag of a struct field.
+func Parse(fieldName string, structTag reflect.StructTag) Tag {
+	res := Tag{Name: fieldName}
+	tag, found := structTag.Lookup("goflat")
+	if !found {
+		return res
+	}
+	if tag == "-" {
+		res.Skip = true
+		return res
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name != "" {
+		res.Name = name
+	}
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if strings.HasPrefix(opt, "key=") {
+			res.Key = strings.TrimPrefix(opt, "key=")
+		}
+	}
+	return res
+}
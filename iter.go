@@ -0,0 +1,48 @@
+//go:build go1.23
+
+package goflat
+
+import (
+	"iter"
+	"reflect"
+)
+
+// Path is a path of a value, which elements are joined with the delimeter.
+type Path string
+
+// All returns an iterator over the leaf values of obj, as Walk reports them, and the containers on the way to them:
+// structs, maps, slices and arrays, which are expanded. Containers are reported before their elements.
+// Breaking the loop stops the walk.
+func All(obj any, opts ...Option) iter.Seq2[Path, any] {
+	return walkSeq(obj, true, true, opts)
+}
+
+// Leaves returns an iterator over the values, which Walk reports.
+// Breaking the loop stops the walk.
+func Leaves(obj any, opts ...Option) iter.Seq2[Path, any] {
+	return walkSeq(obj, true, false, opts)
+}
+
+// Containers returns an iterator over the structs, maps, slices and arrays inside obj, which Walk expands.
+// Breaking the loop stops the walk.
+func Containers(obj any, opts ...Option) iter.Seq2[Path, any] {
+	return walkSeq(obj, false, true, opts)
+}
+
+func walkSeq(obj any, leaves, containers bool, opts []Option) iter.Seq2[Path, any] {
+	return func(yield func(Path, any) bool) {
+		o := makeOptions(opts...)
+		kb := keyBuilder{delim: o.delimeter}
+		cb := func(path []string, value any) bool {
+			return yield(Path(kb.key(path)), value)
+		}
+		w := newWalker(func([]string, any) bool { return true }, o)
+		if leaves {
+			w.cb = cb
+		}
+		if containers {
+			w.ccb = cb
+		}
+		w.run(reflect.ValueOf(obj))
+	}
+}
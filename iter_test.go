@@ -0,0 +1,37 @@
+//go:build go1.23
+
+package goflat
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/avdva/goflat/testpkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterators(t *testing.T) {
+	a := assert.New(t)
+	obj := testpkg.NewTestStruct()
+	leaves := maps.Collect(Leaves(obj))
+	a.Len(leaves, len(Flatten(obj)))
+	for path, value := range Flatten(obj) {
+		a.Equal(value, leaves[Path(path)])
+	}
+	containers := maps.Collect(Containers(obj, SortMapKeys(true)))
+	a.ElementsMatch([]Path{"S", "S.M", "Nested", "Iface", "M", "Slice", "Array"}, slices.Collect(maps.Keys(containers)))
+	a.Equal(obj.Slice, containers["Slice"])
+	all := maps.Collect(All(obj))
+	a.Len(all, len(leaves)+len(containers))
+
+	var ordered []Path
+	for path := range All(obj, SortMapKeys(true)) {
+		ordered = append(ordered, path)
+		if path == "S.M" {
+			break
+		}
+	}
+	a.Equal([]Path{"A", "B", "S", "S.D", "S.Ptr", "S.M"}, ordered)
+}
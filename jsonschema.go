@@ -0,0 +1,362 @@
+package goflat
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// JSONSchemaDraft is the dialect of the schemas returned by JSONSchema.
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// FlatSchema option makes JSONSchema describe flattened documents, whose properties are the keys
+// Flatten produces, instead of nested ones.
+func FlatSchema(flat bool) Option {
+	return func(o *options) {
+		o.flatSchema = flat
+	}
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) for the values of typ, ready for json.Marshal.
+// Documents are structured as for JSONPatch: structs and maps are JSON objects, slices and arrays are JSON arrays,
+// object properties are named as Flatten names the fields, embedded structs are nested under their type names.
+// Pointers, maps and slices may be null. Unknown struct properties are not allowed.
+// Recursive types refer to the schema of their outer value with "$ref".
+// With FlatSchema option the schema describes the documents, which Flatten produces with the same options:
+// the keys without Wildcard elements are properties, the other keys are pattern properties.
+// The kinds, which Walk doesn't report, like channels and functions, are omitted.
+// time.Time values are "date-time" strings, as encoding/json marshals them.
+func JSONSchema(typ reflect.Type, opts ...Option) map[string]interface{} {
+	o := makeOptions(opts...)
+	b := &schemaBuilder{o: o, expanding: make(map[reflect.Type][]string)}
+	if o.flatSchema {
+		b.flat = make(map[string]map[string]interface{})
+	}
+	var res map[string]interface{}
+	if typ != nil {
+		res = b.build(typ, nil, nil)
+	}
+	if o.flatSchema {
+		res = b.flatDocument()
+	}
+	if res == nil {
+		res = map[string]interface{}{}
+	}
+	res["$schema"] = JSONSchemaDraft
+	return res
+}
+
+// schemaBuilder builds nested schemas, and, if flat is not nil, collects the schemas of flattened keys.
+type schemaBuilder struct {
+	o *options
+	// expanding are the types on the current path with the locations of their schemas.
+	expanding map[reflect.Type][]string
+	// flat maps flattened keys to their schemas.
+	flat map[string]map[string]interface{}
+	// keys are the flattened keys in the order they are added.
+	keys []string
+}
+
+// build returns the schema of typ. path is the flattened path of the value, loc is the location of the schema.
+// It returns nil, if Walk doesn't report the values of typ.
+func (b *schemaBuilder) build(typ reflect.Type, path, loc []string) map[string]interface{} {
+	if typ == timeType || (!b.o.expandBytes && isBytes(typ)) || scalarVisitors[typ.Kind()] != nil {
+		s := leafSchema(typ)
+		b.addFlat(path, s)
+		return s
+	}
+	switch typ.Kind() {
+	case reflect.Interface:
+		s := map[string]interface{}{}
+		b.addFlat(path, s)
+		return s
+	case reflect.Pointer:
+		return b.buildPointer(typ, path, loc)
+	case reflect.Struct:
+		return b.buildStruct(typ, path, loc)
+	case reflect.Map:
+		return b.buildMap(typ, path, loc)
+	case reflect.Slice, reflect.Array:
+		return b.buildSliceOrArray(typ, path, loc, b.o.sliceKeys[typ.Elem()])
+	}
+	return nil
+}
+
+// enter marks a type as being expanded. If it is already being expanded, it returns the reference to its schema.
+func (b *schemaBuilder) enter(typ reflect.Type, path, loc []string) map[string]interface{} {
+	if outer, found := b.expanding[typ]; found {
+		b.addFlatRecursive(path)
+		return map[string]interface{}{"$ref": "#" + jsonPointer(outer)}
+	}
+	b.expanding[typ] = append([]string(nil), loc...)
+	return nil
+}
+
+func (b *schemaBuilder) buildPointer(typ reflect.Type, path, loc []string) map[string]interface{} {
+	elem := typ
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if len(path) == 0 {
+		return b.build(elem, path, loc)
+	}
+	policy := b.o.pointerFollowPolicy
+	reported := policy == PointerPolicyJustPointer || policy == PointerPolicyBoth ||
+		(policy == PointerPolicyPrimitivePointer && isPrimitive(elem.Kind()))
+	expanded := policy != PointerPolicyJustPointer && !(policy == PointerPolicyPrimitivePointer && isPrimitive(elem.Kind()))
+	var s map[string]interface{}
+	if expanded {
+		// nil pointers are reported as is.
+		b.addFlat(path, map[string]interface{}{"type": "null"})
+		s = b.build(elem, path, loc)
+	} else {
+		// the flattened value is the pointer itself.
+		flat := b.flat
+		b.flat = nil
+		s = b.build(elem, path, loc)
+		b.flat = flat
+	}
+	if s == nil {
+		return nil
+	}
+	s = nullable(s)
+	if reported {
+		b.addFlat(path, s)
+	}
+	return s
+}
+
+func (b *schemaBuilder) buildStruct(typ reflect.Type, path, loc []string) map[string]interface{} {
+	if ref := b.enter(typ, path, loc); ref != nil {
+		return ref
+	}
+	defer delete(b.expanding, typ)
+	props := make(map[string]interface{})
+	for _, field := range b.o.structFields(typ) {
+		fieldType, fieldPath := typ.Field(field.index).Type, append(path, field.name)
+		fieldLoc := append(loc, "properties", field.name)
+		var s map[string]interface{}
+		if field.key != "" {
+			s = b.buildSliceOrArray(fieldType, fieldPath, fieldLoc, field.key)
+		} else {
+			s = b.build(fieldType, fieldPath, fieldLoc)
+		}
+		if s != nil {
+			props[field.name] = s
+		}
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+func (b *schemaBuilder) buildMap(typ reflect.Type, path, loc []string) map[string]interface{} {
+	if b.o.setMode != SetNone && isSet(typ) {
+		s := map[string]interface{}{
+			"type":        "array",
+			"items":       leafSchema(typ.Key()),
+			"uniqueItems": true,
+		}
+		if b.o.setMode == SetAsValue {
+			b.addFlat(path, s)
+		} else {
+			b.addNilContainer(path)
+			b.build(typ.Key(), append(path, Wildcard), nil)
+		}
+		return nullable(s)
+	}
+	if typ.Key().Kind() != reflect.String {
+		return nil
+	}
+	if ref := b.enter(typ, path, loc); ref != nil {
+		return ref
+	}
+	defer delete(b.expanding, typ)
+	b.addNilContainer(path)
+	elem := b.build(typ.Elem(), append(path, Wildcard), append(loc, "additionalProperties"))
+	if elem == nil {
+		return nil
+	}
+	return nullable(map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": elem,
+	})
+}
+
+func (b *schemaBuilder) buildSliceOrArray(typ reflect.Type, path, loc []string, key string) map[string]interface{} {
+	if b.o.shouldCollapse(typ, path) {
+		var s map[string]interface{}
+		if b.o.collapseMode == CollapseJoin {
+			s = map[string]interface{}{"type": "string"}
+		} else {
+			s = leafSchema(typ)
+		}
+		b.addFlat(path, s)
+		return s
+	}
+	if ref := b.enter(typ, path, loc); ref != nil {
+		return ref
+	}
+	defer delete(b.expanding, typ)
+	var segment string
+	if key != "" {
+		segment = b.o.keyedSegment(typ.Elem(), path, key)
+	}
+	var items map[string]interface{}
+	if segment != "" {
+		if b.flat != nil {
+			// the elements are addressed by index, if the key values repeat.
+			b.build(typ.Elem(), append(path, Wildcard), append(loc, "items"))
+		}
+		// the keyed path replaces the last element of path, so it is built last.
+		items = b.build(typ.Elem(), append(b.o.keyedParent(path), segment), append(loc, "items"))
+	} else {
+		items = b.build(typ.Elem(), append(path, Wildcard), append(loc, "items"))
+	}
+	if items == nil {
+		return nil
+	}
+	s := map[string]interface{}{
+		"type":  "array",
+		"items": items,
+	}
+	if typ.Kind() == reflect.Array {
+		s["maxItems"] = typ.Len()
+		return s
+	}
+	b.addNilContainer(path)
+	return nullable(s)
+}
+
+// addNilContainer adds a flattened key for nil maps and slices, if AddNilContainers option is set.
+func (b *schemaBuilder) addNilContainer(path []string) {
+	if b.o.addNilContainers && len(path) > 0 {
+		b.addFlat(path, map[string]interface{}{"type": "null"})
+	}
+}
+
+// leafSchema returns a schema for a value, which Walk reports as a whole.
+func leafSchema(typ reflect.Type) map[string]interface{} {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch {
+	case typ == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8:
+		return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+	}
+	switch kind := typ.Kind(); {
+	case kind == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case kind == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case kind == reflect.Slice || kind == reflect.Array:
+		s := map[string]interface{}{"type": "array", "items": leafSchema(typ.Elem())}
+		if kind == reflect.Array {
+			s["maxItems"] = typ.Len()
+		}
+		return s
+	}
+	// complex numbers and other values don't have a JSON representation, so any value is allowed.
+	return map[string]interface{}{}
+}
+
+// nullable returns a schema, which also allows null.
+func nullable(s map[string]interface{}) map[string]interface{} {
+	switch t := s["type"].(type) {
+	case string:
+		if t == "null" {
+			return s
+		}
+		res := make(map[string]interface{}, len(s))
+		for k, v := range s {
+			res[k] = v
+		}
+		res["type"] = []string{t, "null"}
+		return res
+	case []string:
+		return s
+	}
+	if len(s) == 0 {
+		return s
+	}
+	return map[string]interface{}{"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+}
+
+// addFlat adds the schema of a flattened key. A key, which may be null, gets a nullable schema.
+func (b *schemaBuilder) addFlat(path []string, s map[string]interface{}) {
+	if b.flat == nil || len(path) == 0 {
+		return
+	}
+	key := strings.Join(path, b.o.delimeter)
+	prev, found := b.flat[key]
+	switch {
+	case !found:
+		b.keys = append(b.keys, key)
+		b.flat[key] = s
+	case prev["type"] == "null":
+		b.flat[key] = nullable(s)
+	case s["type"] == "null":
+		b.flat[key] = nullable(prev)
+	}
+}
+
+// addFlatRecursive adds a recursive value, which is described by a pattern for the keys under it.
+// Any values are allowed for them.
+func (b *schemaBuilder) addFlatRecursive(path []string) {
+	if b.flat == nil || len(path) == 0 {
+		return
+	}
+	key := strings.Join(path, b.o.delimeter) + recursiveSuffix
+	if _, found := b.flat[key]; !found {
+		b.keys = append(b.keys, key)
+		b.flat[key] = map[string]interface{}{}
+	}
+}
+
+// recursiveSuffix marks the keys of recursive values in schemaBuilder.flat.
+const recursiveSuffix = "\x00"
+
+// flatDocument returns the schema of flattened documents.
+func (b *schemaBuilder) flatDocument() map[string]interface{} {
+	props := make(map[string]interface{})
+	patterns := make(map[string]interface{})
+	for _, key := range b.keys {
+		s := b.flat[key]
+		recursive := strings.HasSuffix(key, recursiveSuffix)
+		key = strings.TrimSuffix(key, recursiveSuffix)
+		if !recursive && !strings.Contains(key, Wildcard) {
+			props[key] = s
+			continue
+		}
+		pattern := "^" + b.keyPattern(key)
+		if recursive {
+			pattern += "(" + regexp.QuoteMeta(b.o.delimeter) + ".*)?"
+		}
+		patterns[pattern+"$"] = s
+	}
+	res := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if len(patterns) > 0 {
+		res["patternProperties"] = patterns
+	}
+	return res
+}
+
+// keyPattern returns a regular expression, where Wildcard elements of the key match any path element.
+func (b *schemaBuilder) keyPattern(key string) string {
+	elem := "[^" + regexp.QuoteMeta(b.o.delimeter) + "]+"
+	return strings.ReplaceAll(regexp.QuoteMeta(key), regexp.QuoteMeta(Wildcard), elem)
+}
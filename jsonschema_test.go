@@ -0,0 +1,130 @@
+package goflat
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonSchemaStruct struct {
+	Name    string `goflat:"name"`
+	Port    *int
+	Started time.Time
+	Data    []byte
+	Labels  map[string]string
+	Items   []schemaServer `goflat:",key=Host"`
+	Pair    [2]float32
+	Set     map[string]bool
+	Any     interface{}
+	Root    schemaNode
+	Ignored chan int
+}
+
+func TestJSONSchema(t *testing.T) {
+	a := assert.New(t)
+	s := JSONSchema(reflect.TypeOf(&jsonSchemaStruct{}), WithSetMode(SetAsList))
+	a.Equal(JSONSchemaDraft, s["$schema"])
+	a.Equal("object", s["type"])
+	a.Equal(false, s["additionalProperties"])
+	props := s["properties"].(map[string]interface{})
+	a.Equal(map[string]interface{}{"type": "string"}, props["name"])
+	a.Equal(map[string]interface{}{"type": []string{"integer", "null"}}, props["Port"])
+	a.Equal(map[string]interface{}{"type": "string", "format": "date-time"}, props["Started"])
+	a.Equal(map[string]interface{}{"type": "string", "contentEncoding": "base64"}, props["Data"])
+	a.Equal(map[string]interface{}{
+		"type":                 []string{"object", "null"},
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	}, props["Labels"])
+	a.Equal(map[string]interface{}{
+		"type":     "array",
+		"items":    map[string]interface{}{"type": "number"},
+		"maxItems": 2,
+	}, props["Pair"])
+	a.Equal(map[string]interface{}{
+		"type":        []string{"array", "null"},
+		"items":       map[string]interface{}{"type": "string"},
+		"uniqueItems": true,
+	}, props["Set"])
+	a.Equal(map[string]interface{}{}, props["Any"])
+	a.NotContains(props, "Ignored")
+	items := props["Items"].(map[string]interface{})["items"].(map[string]interface{})
+	a.Contains(items["properties"], "host")
+	root := props["Root"].(map[string]interface{})["properties"].(map[string]interface{})
+	a.Equal(map[string]interface{}{"$ref": "#/properties/Root"}, root["Children"].(map[string]interface{})["additionalProperties"])
+	a.Equal(map[string]interface{}{"anyOf": []interface{}{
+		map[string]interface{}{"$ref": "#/properties/Root"},
+		map[string]interface{}{"type": "null"},
+	}}, root["Next"])
+	_, err := json.Marshal(s)
+	a.NoError(err)
+
+	s = JSONSchema(reflect.TypeOf(schemaNode{}))
+	a.Equal(map[string]interface{}{"$ref": "#"}, s["properties"].(map[string]interface{})["Children"].(map[string]interface{})["additionalProperties"])
+	a.Equal(map[string]interface{}{"$schema": JSONSchemaDraft, "type": "integer"}, JSONSchema(reflect.TypeOf(0)))
+	a.Equal(map[string]interface{}{"$schema": JSONSchemaDraft}, JSONSchema(nil))
+}
+
+// matchFlatSchema returns the schemas, which apply to the key, like a JSON Schema validator would.
+func matchFlatSchema(s map[string]interface{}, key string) []interface{} {
+	var res []interface{}
+	if prop, found := s["properties"].(map[string]interface{})[key]; found {
+		res = append(res, prop)
+	}
+	patterns, _ := s["patternProperties"].(map[string]interface{})
+	for pattern, prop := range patterns {
+		if regexp.MustCompile(pattern).MatchString(key) {
+			res = append(res, prop)
+		}
+	}
+	return res
+}
+
+func TestFlatJSONSchema(t *testing.T) {
+	a := assert.New(t)
+	port := 80
+	obj := jsonSchemaStruct{
+		Name:   "n",
+		Port:   &port,
+		Data:   []byte("data"),
+		Labels: map[string]string{"a.b": "c", "d": "e"},
+		Items:  []schemaServer{{Host: "h1", Weights: []float64{1}}, {Host: "h2"}},
+		Set:    map[string]bool{"x": true},
+		Any:    1,
+		Root: schemaNode{
+			Children: map[string]schemaNode{"c": {Value: 1, Next: &schemaNode{}}},
+		},
+	}
+	dup := obj
+	dup.Items = []schemaServer{{Host: "h"}, {Host: "h"}}
+	for _, opts := range [][]Option{
+		nil,
+		{WithSetMode(SetAsList)},
+		{WithSetMode(SetAsValue), WithPointerFllowPolicy(PointerPolicyJustValue)},
+		{AddNilContainers(true), WithKeyStyle(KeyStyleSegment), WithDelimeter("/")},
+		{CollapsePrimitiveSlices(CollapseJoin, ","), WithPointerFllowPolicy(PointerPolicyBoth)},
+	} {
+		s := JSONSchema(reflect.TypeOf(obj), append(opts, FlatSchema(true))...)
+		a.Equal(JSONSchemaDraft, s["$schema"])
+		a.Equal(false, s["additionalProperties"])
+		for _, o := range []jsonSchemaStruct{obj, dup, {}} {
+			for key := range Flatten(o, opts...) {
+				if key == "Labels.a.b" {
+					// map keys with delimiters are not described.
+					continue
+				}
+				a.NotEmpty(matchFlatSchema(s, key), "%s %v", key, opts)
+			}
+		}
+	}
+	s := JSONSchema(reflect.TypeOf(obj), FlatSchema(true))
+	a.Equal([]interface{}{map[string]interface{}{"type": []string{"integer", "null"}}}, matchFlatSchema(s, "Port"))
+	a.Equal([]interface{}{map[string]interface{}{"type": "string"}}, matchFlatSchema(s, "Items[Host=h1].host"))
+	a.Equal([]interface{}{map[string]interface{}{"type": "string"}}, matchFlatSchema(s, "Items.0.host"))
+	a.Equal([]interface{}{map[string]interface{}{}}, matchFlatSchema(s, "Root.Children.c.Next.Value"))
+	a.Empty(matchFlatSchema(s, "Labels"))
+	a.Empty(matchFlatSchema(s, "None"))
+}
@@ -0,0 +1,247 @@
+package goflat
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// dstType returns the type, whose paths are matched, for a destination of FromEnv or ReadProperties.
+func dstType(dst interface{}) (reflect.Type, error) {
+	typ := reflect.TypeOf(dst)
+	if typ == nil {
+		return nil, fmt.Errorf("%w: %T is not a non-nil pointer or map", ErrUnaddressable, dst)
+	}
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ, nil
+}
+
+// pathNaming defines how paths appear in the names, which pathMatcher matches, e.g. environment variables.
+type pathNaming struct {
+	// sep separates the path elements.
+	sep string
+	// escape and unescape convert path elements to the names and back.
+	escape, unescape func(string) string
+	// wildcard is a regexp group, which matches an escaped map key or key field value.
+	wildcard string
+}
+
+// name returns a name for the path.
+func (n *pathNaming) name(path []string) string {
+	elems := make([]string, len(path))
+	for i, elem := range path {
+		elems[i] = n.escape(elem)
+	}
+	return strings.Join(elems, n.sep)
+}
+
+// pathMatcher matches names against the paths of a type.
+type pathMatcher struct {
+	naming   *pathNaming
+	patterns []pathPattern
+}
+
+// pathPattern matches the names for a path.
+type pathPattern struct {
+	re *regexp.Regexp
+	// elems are the parsed path with Wildcard elements, which are replaced with the submatches.
+	elems []pathElem
+	// wildcards is the number of Wildcard elements in the path.
+	wildcards int
+	// ref is set for the recursive values, see SchemaField.
+	ref []string
+	// refElems is the number of parsed elements in ref.
+	refElems  int
+	recursive bool
+}
+
+// maxNameRecursion limits the depth of recursive values in the names.
+const maxNameRecursion = 32
+
+func newPathMatcher(o *options, typ reflect.Type, naming *pathNaming) *pathMatcher {
+	m := &pathMatcher{naming: naming}
+	for _, field := range o.schema(typ) {
+		for _, path := range indexVariants(splitPath(field.Path, o.delimeter)) {
+			p := pathPattern{elems: parseElems(path), recursive: field.Recursive}
+			if field.Recursive {
+				p.ref = splitPath(field.Ref, o.delimeter)
+				p.refElems = len(parseElems(p.ref))
+			}
+			elems := make([]string, len(path))
+			for i, elem := range path {
+				if elem == indexWildcard {
+					p.wildcards++
+					elems[i] = "([0-9]+)"
+					continue
+				}
+				parts := strings.Split(elem, Wildcard)
+				for j, part := range parts {
+					parts[j] = regexp.QuoteMeta(naming.escape(part))
+				}
+				p.wildcards += len(parts) - 1
+				elems[i] = strings.Join(parts, naming.wildcard)
+			}
+			for i := range p.elems {
+				if p.elems[i].name == indexWildcard {
+					p.elems[i].name = Wildcard
+				}
+			}
+			// the names of recursive values are split after the path, the rest is matched against the paths under ref.
+			p.re = regexp.MustCompile("^" + strings.Join(elems, regexp.QuoteMeta(naming.sep)) + "$")
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	// the paths with fewer wildcards are more specific.
+	sort.SliceStable(m.patterns, func(i, j int) bool {
+		return m.patterns[i].wildcards < m.patterns[j].wildcards
+	})
+	return m
+}
+
+// indexWildcard stands for a slice index in the paths returned by indexVariants.
+const indexWildcard = "\x00#"
+
+// indexVariants returns the path and its variants, where elements like "Items[ID=*]" are replaced
+// with "Items" and an index, as Walk addresses the elements by index, if their key values can't be used, see CheckKeys.
+func indexVariants(path []string) [][]string {
+	variants := [][]string{path}
+	// the elements are replaced from the end, so the indices of the rest stay the same.
+	for i := len(path) - 1; i >= 0; i-- {
+		elem := path[i]
+		if !strings.HasSuffix(elem, "="+Wildcard+"]") {
+			continue
+		}
+		idx := strings.LastIndexByte(elem, '[')
+		if idx < 0 {
+			continue
+		}
+		for _, v := range variants {
+			var indexed []string
+			indexed = append(indexed, v[:i]...)
+			if idx > 0 {
+				indexed = append(indexed, elem[:idx])
+			}
+			indexed = append(indexed, indexWildcard)
+			variants = append(variants, append(indexed, v[i+1:]...))
+		}
+	}
+	return variants
+}
+
+// splitPath splits a flattened key into path elements.
+func splitPath(path, delim string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, delim)
+}
+
+// match returns the parsed path for a name.
+func (m *pathMatcher) match(name string, depth int) ([]pathElem, bool) {
+	if depth > maxNameRecursion {
+		return nil, false
+	}
+	for _, p := range m.patterns {
+		if p.recursive {
+			if path, ok := m.matchRecursive(p, name, depth); ok {
+				return path, true
+			}
+		} else if sub := p.re.FindStringSubmatch(name); sub != nil {
+			return p.expand(sub, m.naming.unescape), true
+		}
+	}
+	return nil, false
+}
+
+// matchRecursive matches a name of a recursive value. The name is split at every separator, the shortest
+// matching part is preferred.
+func (m *pathMatcher) matchRecursive(p pathPattern, name string, depth int) ([]pathElem, bool) {
+	sep := m.naming.sep
+	refName := m.naming.name(p.ref)
+	if refName != "" {
+		refName += sep
+	}
+	for i := 0; i < len(name); i++ {
+		if len(p.elems) == 0 {
+			if i > 0 {
+				break
+			}
+		} else if !strings.HasPrefix(name[i:], sep) {
+			continue
+		}
+		sub := p.re.FindStringSubmatch(name[:i])
+		if sub == nil {
+			continue
+		}
+		rest := name[i:]
+		if len(p.elems) > 0 {
+			rest = rest[len(sep):]
+		}
+		refPath, ok := m.match(refName+rest, depth+1)
+		if ok && len(refPath) >= p.refElems {
+			return append(p.expand(sub, m.naming.unescape), refPath[p.refElems:]...), true
+		}
+	}
+	return nil, false
+}
+
+// expand replaces Wildcard elements of the path with the unescaped submatches.
+// Unlike parsing the result, it keeps map keys, which look like "[key=value]" elements, as is.
+func (p *pathPattern) expand(sub []string, unescape func(string) string) []pathElem {
+	elems := make([]pathElem, len(p.elems))
+	next := 1
+	for i, elem := range p.elems {
+		for n := strings.Count(elem.name, Wildcard); n > 0; n-- {
+			elem.name = strings.Replace(elem.name, Wildcard, unescape(sub[next]), 1)
+			next++
+		}
+		if elem.keyed {
+			elem.keyValue = elem.name[len("["+elem.keyField+"=") : len(elem.name)-1]
+		}
+		elems[i] = elem
+	}
+	return elems
+}
+
+// joinElems joins parsed path elements back into a path.
+func joinElems(elems []pathElem, delim string) string {
+	var sb strings.Builder
+	for i, elem := range elems {
+		if i > 0 && !elem.keyed {
+			sb.WriteString(delim)
+		}
+		sb.WriteString(elem.name)
+	}
+	return sb.String()
+}
+
+// pathChange is a value to be set at path. key is the path joined with the delimeter set by WithDelimeter.
+type pathChange struct {
+	key   string
+	path  []pathElem
+	value string
+}
+
+// applyChanges sets the values as Apply does, but parses times and bytes with the formatter, as they are written.
+// The paths are not parsed from the keys, as map keys may contain the delimiter.
+// If foldKeys is set, key field values are matched case-insensitively.
+func (o *options) applyChanges(dst interface{}, changes []pathChange, foldKeys bool) error {
+	root, err := settableRoot(dst)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].key < changes[j].key
+	})
+	s := &setter{o: o, alloc: true, formatter: o.formatter, foldKeys: foldKeys}
+	for _, c := range changes {
+		if err := s.set(root, c.path, "", c.value); err != nil {
+			return pathError(c.key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package goflat
+
+const raceEnabled = false
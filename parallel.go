@@ -0,0 +1,176 @@
+package goflat
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Parallelism option sets the number of goroutines used by Flatten, FlattenOrdered and FlattenStrings
+// to walk the elements of a top-level slice, array or map. Each worker detects cycles on its own,
+// and the results are merged in the same order as they would be produced by a single goroutine.
+// Other values, as well as the slices with elements addressed by a key field, are walked by a single goroutine.
+// Walk is not affected, as its callback is not expected to be called concurrently.
+func Parallelism(n int) Option {
+	return func(o *options) {
+		o.parallelism = n
+	}
+}
+
+// rootPart is an element of a top-level container, which can be walked independently.
+type rootPart struct {
+	val  reflect.Value
+	name string
+}
+
+// splitRoot returns the elements of a top-level slice, array or map, and the pointers,
+// which are visited on the way to them. It returns nil, if the value can't be split,
+// or if walking it by parts would give different results.
+func (o *options) splitRoot(val reflect.Value) (parts []rootPart, visited []uintptr) {
+	for val.Kind() == reflect.Interface || val.Kind() == reflect.Pointer {
+		if val.Kind() == reflect.Pointer {
+			if val.IsNil() || o.pointerFollowPolicy == PointerPolicyBoth || o.pointerFollowPolicy == PointerPolicyJustPointer {
+				return nil, nil
+			}
+			visited = append(visited, val.Pointer())
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() || (o.isLeaf != nil && o.isLeaf(val.Type())) || (!o.expandBytes && isBytes(val.Type())) {
+		return nil, nil
+	}
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		if (val.Kind() == reflect.Slice && val.IsNil()) || o.shouldCollapse(val.Type(), nil) || o.sliceKeys[val.Type().Elem()] != "" {
+			return nil, nil
+		}
+		if val.Kind() == reflect.Slice {
+			visited = append(visited, val.Pointer())
+		}
+		parts = make([]rootPart, val.Len())
+		for i := range parts {
+			parts[i] = rootPart{val: val.Index(i), name: strconv.Itoa(i)}
+		}
+	case reflect.Map:
+		if val.IsNil() || (o.setMode != SetNone && isSet(val.Type())) || val.Type().Key().Kind() != reflect.String {
+			return nil, nil
+		}
+		visited = append(visited, val.Pointer())
+		keys := val.MapKeys()
+		if o.sortMapKeys {
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].String() < keys[j].String()
+			})
+		}
+		parts = make([]rootPart, len(keys))
+		for i, key := range keys {
+			parts[i] = rootPart{val: val.MapIndex(key), name: key.String()}
+		}
+	}
+	if len(parts) < 2 {
+		return nil, nil
+	}
+	return parts, visited
+}
+
+// walkParallel walks the elements of a top-level container concurrently and returns the reported path-value pairs
+// in the order of the elements. Values are converted with the value function, if it is set.
+// It returns false, if the object can't be split into elements, see splitRoot.
+func walkParallel(val reflect.Value, o *options, value func(v interface{}) interface{}) ([]KV, bool) {
+	parts, visited := o.splitRoot(val)
+	if parts == nil {
+		return nil, false
+	}
+	// use several chunks per worker to balance the load.
+	chunkSize := (len(parts) + o.parallelism*4 - 1) / (o.parallelism * 4)
+	results := make([][]KV, (len(parts)+chunkSize-1)/chunkSize)
+	// stopped marks the chunks, where the walk would be stopped by a single goroutine.
+	stopped := make([]bool, len(results))
+	workers := o.parallelism
+	if workers > len(results) {
+		workers = len(results)
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			kb := keyBuilder{delim: o.delimeter}
+			for {
+				chunk := int(next.Add(1) - 1)
+				if chunk >= len(results) {
+					return
+				}
+				var kvs []KV
+				cb := func(path []string, v interface{}) bool {
+					if value != nil {
+						v = value(v)
+					}
+					kvs = append(kvs, KV{Path: kb.key(path), Value: v})
+					return true
+				}
+				end := (chunk + 1) * chunkSize
+				if end > len(parts) {
+					end = len(parts)
+				}
+				for _, part := range parts[chunk*chunkSize : end] {
+					if !newWalker(cb, o).runAt(part.val, part.name, visited) {
+						stopped[chunk] = true
+						break
+					}
+				}
+				results[chunk] = kvs
+			}
+		}()
+	}
+	wg.Wait()
+	var total int
+	for _, kvs := range results {
+		total += len(kvs)
+	}
+	all := make([]KV, 0, total)
+	for i, kvs := range results {
+		all = append(all, kvs...)
+		if stopped[i] {
+			break
+		}
+	}
+	return all, true
+}
+
+// FlattenBatch flattens the objects concurrently. The number of goroutines is set by Parallelism option,
+// runtime.GOMAXPROCS(0) is used by default. The results are in the same order as the objects.
+func FlattenBatch[T any](objs []T, opts ...Option) []FlatMap {
+	o := makeOptions(opts...)
+	workers := o.parallelism
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(objs) {
+		workers = len(objs)
+	}
+	// the objects are already split, so don't split them further.
+	o.parallelism = 0
+	res := make([]FlatMap, len(objs))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				idx := int(next.Add(1) - 1)
+				if idx >= len(objs) {
+					return
+				}
+				res[idx] = flatten(reflect.ValueOf(objs[idx]), o)
+			}
+		}()
+	}
+	wg.Wait()
+	return res
+}
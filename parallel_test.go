@@ -0,0 +1,63 @@
+package goflat
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/avdva/goflat/testpkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelism(t *testing.T) {
+	a := assert.New(t)
+	items := make([]keyItem, 1000)
+	for i := range items {
+		items[i] = keyItem{ID: i, Name: strconv.Itoa(i)}
+	}
+	m := make(map[string]*keyItem)
+	for i := 0; i < 100; i++ {
+		m[strconv.Itoa(i)] = &items[i]
+	}
+	var cyclic []interface{}
+	cyclic = append(cyclic, 1, &cyclic, []interface{}{2})
+	objs := []interface{}{
+		items, &items, &m, [3]int{1, 2, 3}, []*testpkg.PointerTestStruct{testpkg.NewPointerTestStruct(), nil},
+		cyclic, []int{1}, []byte("bytes"), 5, nil, []interface{}{nil, testpkg.NewTestStruct()},
+	}
+	optsList := [][]Option{
+		nil,
+		{SortMapKeys(true), ExpandUnexported(true), AddNilFields(true)},
+		{WithPointerFllowPolicy(PointerPolicyBoth)},
+		{CollapsePrimitiveSlices(CollapseKeep, "")},
+		{SliceKeyField(reflect.TypeOf(keyItem{}), "ID")},
+	}
+	for _, obj := range objs {
+		for _, opts := range optsList {
+			parallelOpts := append(opts[:len(opts):len(opts)], Parallelism(4))
+			a.Equal(Flatten(obj, opts...), Flatten(obj, parallelOpts...))
+			a.Equal(FlattenStrings(obj, opts...), FlattenStrings(obj, parallelOpts...))
+			if ordered := FlattenOrdered(obj, append(opts, SortMapKeys(true))...); len(ordered) > 0 {
+				a.Equal(ordered, FlattenOrdered(obj, append(parallelOpts, SortMapKeys(true))...))
+			}
+		}
+	}
+}
+
+func TestFlattenBatch(t *testing.T) {
+	a := assert.New(t)
+	objs := make([]*testpkg.PointerTestStruct, 100)
+	for i := range objs {
+		objs[i] = testpkg.NewPointerTestStruct()
+		*objs[i].IntPtr = i
+	}
+	for _, opts := range [][]Option{nil, {Parallelism(3)}, {Parallelism(1000), ExpandUnexported(true)}} {
+		res := FlattenBatch(objs, opts...)
+		a.Len(res, len(objs))
+		for i, obj := range objs {
+			a.Equal(Flatten(obj, opts...), res[i])
+		}
+	}
+	a.Empty(FlattenBatch([]int(nil)))
+}
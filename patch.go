@@ -0,0 +1,249 @@
+package goflat
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is an RFC 6902 JSON Patch operation.
+type PatchOperation struct {
+	// Op is one of "add", "remove", "replace".
+	Op string
+	// Path is a JSON Pointer (RFC 6901) to the target location.
+	Path string
+	// Value is a value to add or replace with. It is omitted for "remove" operations.
+	Value interface{}
+}
+
+// MarshalJSON encodes the operation as a JSON object.
+func (op PatchOperation) MarshalJSON() ([]byte, error) {
+	if op.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{Op: op.Op, Path: op.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{Op: op.Op, Path: op.Path, Value: op.Value})
+}
+
+// JSONPatch computes the difference between two objects as an RFC 6902 JSON Patch.
+// Documents are structured as Flatten would name the elements: structs and maps are JSON objects,
+// slices and arrays are JSON arrays, so the patch applies to a JSON document built by the same rules.
+// Slice elements are always compared by index, so moved elements are expressed as replace operations.
+// The options are the same as for Diff.
+func JSONPatch(a, b interface{}, opts ...Option) []PatchOperation {
+	o := makeOptions(opts...)
+	d := newDiffer(o)
+	d.indexOnly = true
+	changes := d.run(a, b)
+	reverseRemovals(changes)
+	tb := &treeBuilder{o: o, visited: make(map[uintptr]struct{})}
+	ops := make([]PatchOperation, 0, len(changes))
+	for _, change := range changes {
+		op := PatchOperation{Path: jsonPointer(change.elems)}
+		switch change.Op {
+		case ChangeAdded:
+			op.Op = "add"
+		case ChangeRemoved:
+			op.Op = "remove"
+		default:
+			op.Op = "replace"
+		}
+		if change.Op != ChangeRemoved {
+			op.Value, _ = tb.build(reflect.ValueOf(change.New), change.elems)
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// reverseRemovals reverses the order of consecutive removals of slice elements,
+// so that removing an element doesn't shift the indices of the next ones.
+func reverseRemovals(changes []Change) {
+	isElemRemoval := func(c Change) bool {
+		if c.Op != ChangeRemoved || len(c.elems) == 0 {
+			return false
+		}
+		_, err := strconv.Atoi(c.elems[len(c.elems)-1])
+		return err == nil
+	}
+	sameParent := func(a, b Change) bool {
+		return len(a.elems) == len(b.elems) && reflect.DeepEqual(a.elems[:len(a.elems)-1], b.elems[:len(b.elems)-1])
+	}
+	for i := 0; i < len(changes); {
+		j := i + 1
+		if isElemRemoval(changes[i]) {
+			for j < len(changes) && isElemRemoval(changes[j]) && sameParent(changes[i], changes[j]) {
+				j++
+			}
+			for l, r := i, j-1; l < r; l, r = l+1, r-1 {
+				changes[l], changes[r] = changes[r], changes[l]
+			}
+		}
+		i = j
+	}
+}
+
+// jsonPointer builds an RFC 6901 JSON Pointer from path elements.
+func jsonPointer(path []string) string {
+	var sb strings.Builder
+	replacer := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, elem := range path {
+		sb.WriteByte('/')
+		sb.WriteString(replacer.Replace(elem))
+	}
+	return sb.String()
+}
+
+// MergePatch computes the difference between two objects as an RFC 7386 JSON Merge Patch document.
+// The documents are structured as for JSONPatch. As required by the RFC, a change inside
+// an array replaces the whole array, and removed values are set to null.
+// The options are the same as for Diff.
+func MergePatch(a, b interface{}, opts ...Option) interface{} {
+	o := makeOptions(opts...)
+	d := newDiffer(o)
+	d.indexOnly = true
+	changes := d.run(a, b)
+	tb := &treeBuilder{o: o, visited: make(map[uintptr]struct{})}
+	target, _ := tb.build(reflect.ValueOf(b), nil)
+	var patch interface{} = make(map[string]interface{})
+	for _, change := range changes {
+		node := target
+		for i, elem := range change.elems {
+			m, isMap := node.(map[string]interface{})
+			if !isMap {
+				patch = setPatchValue(patch, change.elems[:i], node)
+				break
+			}
+			node = m[elem]
+			if i == len(change.elems)-1 {
+				patch = setPatchValue(patch, change.elems, node)
+			}
+		}
+		if len(change.elems) == 0 {
+			return target
+		}
+	}
+	return patch
+}
+
+// setPatchValue sets a value at the path inside the patch document creating intermediate objects.
+// It doesn't go inside the values, which were already set.
+func setPatchValue(patch interface{}, path []string, value interface{}) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+	current, _ := patch.(map[string]interface{})
+	for _, elem := range path[:len(path)-1] {
+		if current == nil {
+			return patch
+		}
+		next, found := current[elem]
+		if !found {
+			next = make(map[string]interface{})
+			current[elem] = next
+		}
+		current, _ = next.(map[string]interface{})
+	}
+	if current != nil {
+		current[path[len(path)-1]] = value
+	}
+	return patch
+}
+
+// treeBuilder converts objects to trees of map[string]interface{}, []interface{} and leaf values,
+// naming the elements as Walk does.
+type treeBuilder struct {
+	o       *options
+	visited map[uintptr]struct{}
+}
+
+// build returns a tree for the value. The second result is false for the values, which Walk skips.
+func (t *treeBuilder) build(val reflect.Value, path []string) (interface{}, bool) {
+	for val.Kind() == reflect.Interface {
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return nil, true
+	}
+	if (t.o.isLeaf != nil && t.o.isLeaf(val.Type())) || (!t.o.expandBytes && isBytes(val.Type())) {
+		return valueInterface(val), true
+	}
+	switch kind := val.Kind(); kind {
+	case reflect.Pointer:
+		if val.IsNil() {
+			return nil, true
+		}
+		if !t.enter(val) {
+			return nil, false
+		}
+		defer delete(t.visited, val.Pointer())
+		return t.build(val.Elem(), path)
+	case reflect.Struct:
+		res := make(map[string]interface{})
+		for _, field := range t.o.structFields(val.Type()) {
+			if v, ok := t.build(val.Field(field.index), append(path, field.name)); ok {
+				res[field.name] = v
+			}
+		}
+		return res, true
+	case reflect.Map:
+		if t.o.setMode != SetNone && isSet(val.Type()) {
+			return t.build(setMembers(val), path)
+		}
+		if val.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		if val.IsNil() {
+			return nil, true
+		}
+		if !t.enter(val) {
+			return nil, false
+		}
+		defer delete(t.visited, val.Pointer())
+		res := make(map[string]interface{}, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			if v, ok := t.build(iter.Value(), append(path, key)); ok {
+				res[key] = v
+			}
+		}
+		return res, true
+	case reflect.Slice, reflect.Array:
+		if kind == reflect.Slice {
+			if val.IsNil() {
+				return nil, true
+			}
+			if !t.enter(val) {
+				return nil, false
+			}
+			defer delete(t.visited, val.Pointer())
+		}
+		if t.o.shouldCollapse(val.Type(), path) {
+			return t.o.collapsedValue(val), true
+		}
+		res := make([]interface{}, val.Len())
+		for i := range res {
+			res[i], _ = t.build(val.Index(i), append(path, strconv.Itoa(i)))
+		}
+		return res, true
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return nil, false
+	}
+	return valueInterface(val), true
+}
+
+func (t *treeBuilder) enter(val reflect.Value) bool {
+	if _, found := t.visited[val.Pointer()]; found {
+		return false
+	}
+	t.visited[val.Pointer()] = struct{}{}
+	return true
+}
@@ -0,0 +1,124 @@
+package goflat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type patchItem struct {
+	ID   int
+	Tags []string
+}
+
+type patchStruct struct {
+	Name  string
+	M     map[string]interface{}
+	Items []patchItem `goflat:",key=ID"`
+	Ptr   *patchItem
+	Bytes []byte
+}
+
+func TestJSONPatch(t *testing.T) {
+	a := assert.New(t)
+	x := patchStruct{
+		Name: "x",
+		M:    map[string]interface{}{"a/b": 1, "c~d": 2, "e": 3},
+		Items: []patchItem{
+			{ID: 1, Tags: []string{"a", "b", "c"}},
+			{ID: 2},
+			{ID: 3},
+		},
+		Bytes: []byte("x"),
+	}
+	y := patchStruct{
+		Name: "y",
+		M:    map[string]interface{}{"a/b": 10, "f": map[string]int{"g": 1}},
+		Items: []patchItem{
+			{ID: 2, Tags: []string{"a"}},
+		},
+		Ptr:   &patchItem{ID: 5},
+		Bytes: []byte("y"),
+	}
+	ops := JSONPatch(x, y)
+	a.Equal([]PatchOperation{
+		{Op: "replace", Path: "/Name", Value: "y"},
+		{Op: "replace", Path: "/M/a~1b", Value: 10},
+		{Op: "remove", Path: "/M/c~0d"},
+		{Op: "remove", Path: "/M/e"},
+		{Op: "add", Path: "/M/f", Value: map[string]interface{}{"g": 1}},
+		{Op: "replace", Path: "/Items/0/ID", Value: 2},
+		{Op: "remove", Path: "/Items/0/Tags/2"},
+		{Op: "remove", Path: "/Items/0/Tags/1"},
+		{Op: "remove", Path: "/Items/2"},
+		{Op: "remove", Path: "/Items/1"},
+		{Op: "replace", Path: "/Ptr", Value: map[string]interface{}{"ID": 5, "Tags": nil}},
+		{Op: "replace", Path: "/Bytes", Value: []byte("y")},
+	}, ops)
+	data, err := json.Marshal(ops[:3])
+	a.NoError(err)
+	a.JSONEq(`[
+		{"op": "replace", "path": "/Name", "value": "y"},
+		{"op": "replace", "path": "/M/a~1b", "value": 10},
+		{"op": "remove", "path": "/M/c~0d"}
+	]`, string(data))
+	a.Empty(JSONPatch(x, x))
+	a.Equal([]PatchOperation{{Op: "replace", Path: "", Value: 2}}, JSONPatch(1, 2))
+}
+
+func TestMergePatch(t *testing.T) {
+	a := assert.New(t)
+	x := patchStruct{
+		Name:  "x",
+		M:     map[string]interface{}{"a": 1, "b": map[string]int{"c": 1, "d": 2}},
+		Items: []patchItem{{ID: 1}, {ID: 2}},
+	}
+	y := patchStruct{
+		Name:  "x",
+		M:     map[string]interface{}{"b": map[string]int{"c": 2, "d": 2}, "e": 3},
+		Items: []patchItem{{ID: 1, Tags: []string{"t"}}, {ID: 2}},
+	}
+	patch := MergePatch(x, y)
+	a.Equal(map[string]interface{}{
+		"M": map[string]interface{}{
+			"a": nil,
+			"b": map[string]interface{}{"c": 2},
+			"e": 3,
+		},
+		"Items": []interface{}{
+			map[string]interface{}{"ID": 1, "Tags": []interface{}{"t"}},
+			map[string]interface{}{"ID": 2, "Tags": nil},
+		},
+	}, patch)
+	data, err := json.Marshal(patch)
+	a.NoError(err)
+	a.JSONEq(`{"M": {"a": null, "b": {"c": 2}, "e": 3}, "Items": [{"ID": 1, "Tags": ["t"]}, {"ID": 2, "Tags": null}]}`, string(data))
+	a.Equal(map[string]interface{}{}, MergePatch(x, x))
+	a.Equal(2, MergePatch(1, 2))
+	a.Equal(map[string]interface{}{"Name": "y"}, MergePatch(x, patchStruct{
+		Name:  "y",
+		M:     x.M,
+		Items: x.Items,
+	}, IgnorePaths("M", "Items")))
+}
+
+func TestJSONPatchNilContainers(t *testing.T) {
+	a := assert.New(t)
+	empty := patchStruct{}
+	full := patchStruct{
+		M:     map[string]interface{}{"k": 1},
+		Items: []patchItem{{ID: 1}},
+	}
+	a.Equal([]PatchOperation{
+		{Op: "replace", Path: "/M", Value: map[string]interface{}{"k": 1}},
+		{Op: "replace", Path: "/Items", Value: []interface{}{map[string]interface{}{"ID": 1, "Tags": nil}}},
+	}, JSONPatch(empty, full))
+	a.Equal([]PatchOperation{
+		{Op: "replace", Path: "/M", Value: nil},
+		{Op: "replace", Path: "/Items", Value: nil},
+	}, JSONPatch(full, empty))
+	a.Equal([]PatchOperation{{Op: "replace", Path: "/Items/0/Tags", Value: []interface{}{"a"}}},
+		JSONPatch(patchStruct{Items: []patchItem{{ID: 1}}}, patchStruct{Items: []patchItem{{ID: 1, Tags: []string{"a"}}}}))
+	a.Equal(map[string]interface{}{"M": nil, "Items": nil}, MergePatch(full, empty))
+}
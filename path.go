@@ -0,0 +1,224 @@
+package goflat
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrIndexOutOfRange is returned when a slice or an array index is out of range.
+	ErrIndexOutOfRange = errors.New("goflat: index out of range")
+	// ErrUnaddressable is returned when a value can't be set.
+	ErrUnaddressable = errors.New("goflat: unaddressable value")
+)
+
+// PathError records an error and the path that caused it.
+type PathError struct {
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("goflat: %q: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// pathElem is a parsed path element.
+type pathElem struct {
+	name string
+	// keyField and keyValue are set for "[key=value]" elements, see KeyStyleBracket.
+	keyField string
+	keyValue string
+	keyed    bool
+}
+
+// parsePath splits the path into elements. Elements like "Items[ID=42]" are split into "Items" and "[ID=42]".
+func parsePath(path, delim string) []pathElem {
+	if path == "" {
+		return nil
+	}
+	return parseElems(strings.Split(path, delim))
+}
+
+// parseElems is like parsePath for a path, which is already split by the delimiter.
+func parseElems(parts []string) []pathElem {
+	elems := make([]pathElem, 0, len(parts))
+	for _, part := range parts {
+		if strings.HasSuffix(part, "]") {
+			if idx := strings.LastIndexByte(part, '['); idx >= 0 {
+				if field, value, found := strings.Cut(part[idx+1:len(part)-1], "="); found {
+					if idx > 0 {
+						elems = append(elems, pathElem{name: part[:idx]})
+					}
+					elems = append(elems, pathElem{name: part[idx:], keyField: field, keyValue: value, keyed: true})
+					continue
+				}
+			}
+		}
+		elems = append(elems, pathElem{name: part})
+	}
+	return elems
+}
+
+// findKeyIndex returns an index of the slice element with the key field, formatted as a string, equal to value,
+// or -1, if there is no such an element. If fold is set, the strings are compared case-insensitively.
+func (o *options) findKeyIndex(v reflect.Value, key, value string, fold bool) int {
+	f := o.formatter
+	if f == nil {
+		f = NewValueFormatter()
+	}
+	for i := 0; i < v.Len(); i++ {
+		field, ok := keyFieldValue(v.Index(i), key)
+		if !ok {
+			continue
+		}
+		if formatted := f.format(field); formatted == value || (fold && strings.EqualFold(formatted, value)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// lookup returns the element at path inside v.
+// key is a key field name, if v is a slice addressed by key field.
+func (o *options) lookup(v reflect.Value, path []pathElem, key string) (reflect.Value, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return reflect.Value{}, fmt.Errorf("%w: nil %v", ErrNotFound, v.Type())
+		}
+		return o.lookup(v.Elem(), path, key)
+	case reflect.Struct:
+		for _, field := range o.structFields(v.Type()) {
+			if field.name == path[0].name && !path[0].keyed {
+				return o.lookup(v.Field(field.index), path[1:], field.key)
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("%w: no field %q in %v", ErrNotFound, path[0].name, v.Type())
+	case reflect.Map:
+		if path[0].keyed {
+			return reflect.Value{}, fmt.Errorf("%w: %q is not a map key", ErrNotFound, path[0].name)
+		}
+		k, err := convert(path[0].name, v.Type().Key())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		elem := v.MapIndex(k)
+		if !elem.IsValid() {
+			return reflect.Value{}, fmt.Errorf("%w: no key %q", ErrNotFound, path[0].name)
+		}
+		return o.lookup(elem, path[1:], "")
+	case reflect.Slice, reflect.Array:
+		if key == "" {
+			key = o.sliceKeys[v.Type().Elem()]
+		}
+		idx := -1
+		switch {
+		case path[0].keyed:
+			idx = o.findKeyIndex(v, path[0].keyField, path[0].keyValue, false)
+		case key != "" && o.keyStyle == KeyStyleSegment && !strings.HasPrefix(path[0].name, IndexPrefix):
+			idx = o.findKeyIndex(v, key, path[0].name, false)
+		default:
+			n, ok := o.parseIndex(path[0].name, key)
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("%w: bad index %q", ErrNotFound, path[0].name)
+			}
+			if n >= v.Len() {
+				return reflect.Value{}, fmt.Errorf("%w: %d, length %d", ErrIndexOutOfRange, n, v.Len())
+			}
+			idx = n
+		}
+		if idx < 0 {
+			return reflect.Value{}, fmt.Errorf("%w: no element %q", ErrNotFound, path[0].name)
+		}
+		return o.lookup(v.Index(idx), path[1:], "")
+	}
+	return reflect.Value{}, fmt.Errorf("%w: can't find %q in %v", ErrNotFound, path[0].name, v.Type())
+}
+
+// Get returns the value at path inside obj. The path is resolved with the same naming rules as Walk uses,
+// without walking the whole object. Pointers to non-primitive types are followed according to the pointer policy,
+// e.g. by default Get returns *int for a pointer to int, and a struct value for a pointer to struct.
+// Errors are returned as *PathError, wrapping ErrNotFound or ErrIndexOutOfRange.
+// Values of unexported fields can be read, if ExpandUnexported is set, but only primitive values are copied,
+// for other types ErrUnaddressable is returned.
+func Get(obj interface{}, path string, opts ...Option) (interface{}, error) {
+	o := makeOptions(opts...)
+	val, err := o.lookup(reflect.ValueOf(obj), parsePath(path, o.delimeter), "")
+	if err != nil {
+		return nil, pathError(path, err)
+	}
+	val = o.followPointer(val)
+	if !val.IsValid() || val.CanInterface() {
+		return valueInterface(val), nil
+	}
+	if isScalar(val.Kind()) {
+		return copyValue(val).Interface(), nil
+	}
+	return nil, pathError(path, fmt.Errorf("%w: unexported %v", ErrUnaddressable, val.Type()))
+}
+
+// followPointer dereferences non-nil pointers, if Walk would report the underlying value instead of the pointer.
+func (o *options) followPointer(val reflect.Value) reflect.Value {
+	if val.Kind() != reflect.Pointer || val.IsNil() {
+		return val
+	}
+	elem := val
+	for elem.Kind() == reflect.Pointer && !elem.IsNil() {
+		elem = elem.Elem()
+	}
+	switch o.pointerFollowPolicy {
+	case PointerPolicyJustValue:
+		return elem
+	case PointerPolicyPrimitivePointer:
+		if !isPrimitive(elem.Kind()) {
+			return elem
+		}
+	}
+	return val
+}
+
+// Set sets the value at path inside obj, which must be a non-nil pointer or a map.
+// The path is resolved with the same naming rules as Walk uses, and value is converted to the target type.
+// Unlike Apply, Set doesn't allocate anything: nil pointers and maps on the way, as well as out of range indices,
+// are errors. Errors are returned as *PathError, wrapping ErrNotFound, ErrIndexOutOfRange or ErrUnaddressable,
+// or as *ConversionError.
+func Set(obj interface{}, path string, value interface{}, opts ...Option) error {
+	o := makeOptions(opts...)
+	root, err := settableRoot(obj)
+	if err != nil {
+		return err
+	}
+	s := &setter{o: o}
+	if err := s.set(root, parsePath(path, o.delimeter), "", value); err != nil {
+		return pathError(path, err)
+	}
+	return nil
+}
+
+// GetAs is like Get, but converts the value to type V. Values are converted as Apply does,
+// e.g. strings are parsed into numbers. Conversion errors are returned as *ConversionError.
+func GetAs[V any](obj interface{}, path string, opts ...Option) (V, error) {
+	var res V
+	val, err := Get(obj, path, opts...)
+	if err != nil {
+		return res, err
+	}
+	if v, ok := val.(V); ok {
+		return v, nil
+	}
+	converted, err := convert(val, reflect.TypeOf(&res).Elem())
+	if err != nil {
+		return res, pathError(path, err)
+	}
+	return converted.Interface().(V), nil
+}
@@ -0,0 +1,128 @@
+package goflat
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/avdva/goflat/testpkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	a := assert.New(t)
+	obj := testpkg.NewTestStruct()
+	for path, exp := range Flatten(obj) {
+		val, err := Get(obj, path)
+		a.NoError(err, path)
+		a.Equal(exp, val, path)
+	}
+	val, err := Get(obj, "S.Ptr", WithPointerFllowPolicy(PointerPolicyJustValue))
+	a.NoError(err)
+	a.Equal(123, val)
+	val, err = Get(obj, "Nested")
+	a.NoError(err)
+	a.Equal(Flatten(obj).Sub("Nested").Unflatten(), Flatten(val).Unflatten())
+	val, err = Get(obj, "notExportedInt", ExpandUnexported(true))
+	a.NoError(err)
+	a.Equal(123, val)
+	items := struct {
+		Items []keyItem
+	}{
+		Items: []keyItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}},
+	}
+	val, err = Get(items, "Items[ID=2].Name", SliceKeyField(reflect.TypeOf(keyItem{}), "ID"))
+	a.NoError(err)
+	a.Equal("b", val)
+	val, err = Get(items, "Items/1/Name", SliceKeyField(reflect.TypeOf(keyItem{}), "ID"), WithKeyStyle(KeyStyleSegment), WithDelimeter("/"))
+	a.NoError(err)
+	a.Equal("a", val)
+}
+
+func TestGetErrors(t *testing.T) {
+	a := assert.New(t)
+	obj := testpkg.NewTestStruct()
+	tests := []struct {
+		path string
+		opts []Option
+		err  error
+	}{
+		{path: "None", err: ErrNotFound},
+		{path: "A.B", err: ErrNotFound},
+		{path: "M.none", err: ErrNotFound},
+		{path: "Slice.3", err: ErrIndexOutOfRange},
+		{path: "Slice.x", err: ErrNotFound},
+		{path: "notExportedInt", err: ErrNotFound},
+		{path: "notExportedStruct", opts: []Option{ExpandUnexported(true)}, err: ErrUnaddressable},
+	}
+	for _, test := range tests {
+		_, err := Get(obj, test.path, test.opts...)
+		a.True(errors.Is(err, test.err), "%s: %v", test.path, err)
+		var pathErr *PathError
+		a.True(errors.As(err, &pathErr))
+		a.Equal(test.path, pathErr.Path)
+	}
+}
+
+func TestSet(t *testing.T) {
+	a := assert.New(t)
+	obj := testpkg.NewTestStruct()
+	a.NoError(Set(obj, "Slice.1", 2.5))
+	a.NoError(Set(obj, "S.Ptr", "5"))
+	a.NoError(Set(obj, "M.key", "new"))
+	a.NoError(Set(obj, "Iface.Val", "changed"))
+	flat := Flatten(obj)
+	a.Equal(2.5, flat["Slice.1"])
+	a.Equal(5, *flat["S.Ptr"].(*int))
+	a.Equal("new", flat["M.key"])
+	a.Equal("changed", flat["Iface.Val"])
+	tests := []struct {
+		path  string
+		value interface{}
+		opts  []Option
+		err   error
+	}{
+		{path: "None", value: 1, err: ErrNotFound},
+		{path: "Slice.3", value: 1, err: ErrIndexOutOfRange},
+		{path: "Array.3", value: 1, err: ErrIndexOutOfRange},
+		{path: "A", value: "x", err: ErrConversion},
+		{path: "notExportedInt", value: 1, opts: []Option{ExpandUnexported(true)}, err: ErrUnaddressable},
+		{path: "NilSlice.0", value: 1, err: ErrIndexOutOfRange},
+	}
+	for _, test := range tests {
+		err := Set(obj, test.path, test.value, test.opts...)
+		a.True(errors.Is(err, test.err), "%s: %v", test.path, err)
+	}
+	a.True(errors.Is(Set(*obj, "A", 1), ErrUnaddressable))
+	var nilPtr struct{ P *struct{ A int } }
+	a.True(errors.Is(Set(&nilPtr, "P.A", 1), ErrUnaddressable))
+}
+
+func TestGetAs(t *testing.T) {
+	a := assert.New(t)
+	obj := struct {
+		Int   int
+		Str   string
+		Items []keyItem
+	}{
+		Int:   5,
+		Str:   "42",
+		Items: []keyItem{{ID: 1, Name: "a"}},
+	}
+	i, err := GetAs[int](obj, "Int")
+	a.NoError(err)
+	a.Equal(5, i)
+	i, err = GetAs[int](obj, "Str")
+	a.NoError(err)
+	a.Equal(42, i)
+	f, err := GetAs[float64](obj, "Items.0.ID")
+	a.NoError(err)
+	a.Equal(1.0, f)
+	_, err = GetAs[int](obj, "None")
+	a.True(errors.Is(err, ErrNotFound), "%v", err)
+	_, err = GetAs[bool](obj, "Items.0.Name")
+	var convErr *ConversionError
+	a.True(errors.As(err, &convErr), "%v", err)
+	a.Equal("Items.0.Name", convErr.Path)
+}
@@ -0,0 +1,216 @@
+package goflat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// WriteProperties writes a golang object as a Java .properties file with sorted keys and values as returned by FlattenStrings.
+// Nil values are skipped. Keys and values are escaped as by java.util.Properties.store: the characters outside
+// of printable ASCII are written as \uXXXX escapes, so the file is valid both in ISO 8859-1 and UTF-8.
+func WriteProperties(w io.Writer, obj interface{}, opts ...Option) error {
+	o := makeStringOptions(opts...)
+	m := make(map[string]string)
+	o.formatLeaves(obj, func(path []string, value string) {
+		m[strings.Join(path, o.delimeter)] = value
+	})
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	bw := bufio.NewWriter(w)
+	for _, key := range keys {
+		bw.WriteString(escapeProperty(key, true))
+		bw.WriteByte('=')
+		bw.WriteString(escapeProperty(m[key], false))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+func escapeProperty(s string, isKey bool) string {
+	var sb strings.Builder
+	for i, r := range s {
+		switch {
+		case r == ' ':
+			if isKey || i == 0 {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(' ')
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\f':
+			sb.WriteString(`\f`)
+		case r == '=' || r == ':' || r == '#' || r == '!' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&sb, `\u%04X`, u)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// ReadProperties reads a Java .properties file and sets the values in dst, as Apply does.
+// The file is parsed as by java.util.Properties.load, but it is read as UTF-8.
+// Like in FromEnv, keys are matched against the paths, which Flatten may produce for dst, see Schema,
+// so map keys may contain the delimeter, e.g. "Labels.app.kubernetes.io/name", and times and bytes are parsed
+// with the formatter set by WithValueFormatter.
+// ReadProperties returns the sorted keys, which don't match any path.
+// Options, that affect naming, must match those used for WriteProperties.
+func ReadProperties(r io.Reader, dst interface{}, opts ...Option) (unknown []string, err error) {
+	props, err := parseProperties(r)
+	if err != nil {
+		return nil, err
+	}
+	o := makeStringOptions(opts...)
+	typ, err := dstType(dst)
+	if err != nil {
+		return nil, err
+	}
+	m := newPathMatcher(o, typ, &pathNaming{sep: o.delimeter, escape: identity, unescape: identity, wildcard: "(.+?)"})
+	var changes []pathChange
+	for key, value := range props {
+		path, ok := m.match(key, 0)
+		if !ok {
+			unknown = append(unknown, key)
+			continue
+		}
+		changes = append(changes, pathChange{key: key, path: path, value: value})
+	}
+	sort.Strings(unknown)
+	if len(changes) == 0 {
+		return unknown, nil
+	}
+	return unknown, o.applyChanges(dst, changes, false)
+}
+
+func identity(s string) string {
+	return s
+}
+
+// parseProperties returns the key-value pairs from a .properties file.
+func parseProperties(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.ReplaceAll(strings.ReplaceAll(string(data), "\r\n", "\n"), "\r", "\n"), "\n")
+	props := make(map[string]string)
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// a line ending with an odd number of backslashes continues on the next line.
+		for continues(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if continues(line) {
+			line = line[:len(line)-1]
+		}
+		key, value, err := splitProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrBadSyntax, lineNum, err)
+		}
+		props[key] = value
+	}
+	return props, nil
+}
+
+func continues(line string) bool {
+	var n int
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits a logical line into an unescaped key and value.
+func splitProperty(line string) (key, value string, err error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if c := line[i]; c == '\\' {
+			i++
+		} else if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			end = i
+			break
+		}
+	}
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	if key, err = unescapeProperty(line[:end]); err != nil {
+		return "", "", err
+	}
+	if value, err = unescapeProperty(rest); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var sb strings.Builder
+	var units []uint16
+	flush := func() {
+		sb.WriteString(string(utf16.Decode(units)))
+		units = units[:0]
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			flush()
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		if s[i] == 'u' {
+			if i+5 > len(s) {
+				return "", fmt.Errorf("bad unicode escape %q", s[i-1:])
+			}
+			u, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("bad unicode escape %q", s[i-1:i+5])
+			}
+			// surrogate pairs are decoded together.
+			units = append(units, uint16(u))
+			i += 4
+			continue
+		}
+		flush()
+		switch s[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	flush()
+	return sb.String(), nil
+}
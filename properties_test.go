@@ -0,0 +1,116 @@
+package goflat
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProperties(t *testing.T) {
+	a := assert.New(t)
+	port := 80
+	src := envConfig{
+		Name:    " leading space, = : # ! \\ \t\n\r\f тест 😀",
+		Port:    &port,
+		Timeout: time.Second,
+		Started: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Labels:  map[string]string{"env": "prod", "a b": "c"},
+		Servers: []envServer{{Host: "h1", Weight: 1.5}},
+		Root:    schemaNode{Value: 1, Next: &schemaNode{Value: 2}},
+	}
+	var buf bytes.Buffer
+	a.NoError(WriteProperties(&buf, src))
+	out := buf.String()
+	a.Contains(out, "port=80\n")
+	a.Contains(out, "Labels.a\\ b=c\n")
+	a.Contains(out, "Servers[Host\\=h1].Weight=1.5\n")
+	a.Contains(out, `Name=\ leading space, \= \: \# \! \\ \t\n\r\f \u0442\u0435\u0441\u0442 \uD83D\uDE00`+"\n")
+
+	var dst envConfig
+	unknown, err := ReadProperties(&buf, &dst)
+	a.NoError(err)
+	a.Empty(unknown)
+	a.Equal(src, dst)
+}
+
+func TestPropertiesKeys(t *testing.T) {
+	a := assert.New(t)
+	src := envConfig{
+		Labels:  map[string]string{"app.kubernetes.io/name": "x", "env": "prod"},
+		Servers: []envServer{{Host: "h1.example.com", Weight: 1.5}},
+		Root:    schemaNode{Value: 1, Children: map[string]schemaNode{"a.b": {Value: 2}}},
+	}
+	var buf bytes.Buffer
+	a.NoError(WriteProperties(&buf, src))
+	a.Contains(buf.String(), "Labels.app.kubernetes.io/name=x\n")
+	buf.WriteString("Other=1\nLabels=2\n")
+	var dst envConfig
+	unknown, err := ReadProperties(&buf, &dst)
+	a.NoError(err)
+	a.Equal([]string{"Labels", "Other"}, unknown)
+	a.Equal(src, dst)
+}
+
+func TestPropertiesFormatter(t *testing.T) {
+	type formatted struct {
+		Bytes   []byte
+		Array   [3]byte
+		Time    time.Time
+		TimePtr *time.Time
+	}
+	a := assert.New(t)
+	tm := time.Date(2023, 1, 2, 3, 4, 0, 0, time.UTC)
+	src := formatted{Bytes: []byte{1, 2, 3}, Array: [3]byte{4, 5, 6}, Time: tm, TimePtr: &tm}
+	for _, enc := range []BytesEncoding{BytesBase64, BytesHex, BytesRaw} {
+		f := NewValueFormatter()
+		f.BytesEncoding, f.TimeLayout = enc, "2006-01-02 15:04"
+		var buf bytes.Buffer
+		a.NoError(WriteProperties(&buf, src, WithValueFormatter(f)))
+		a.Contains(buf.String(), "Time=2023-01-02 03\\:04\n")
+		var dst formatted
+		unknown, err := ReadProperties(&buf, &dst, WithValueFormatter(f))
+		a.NoError(err)
+		a.Empty(unknown)
+		a.Equal(src, dst, "encoding %d", enc)
+	}
+}
+
+func TestReadProperties(t *testing.T) {
+	a := assert.New(t)
+	input := "# comment\n" +
+		"  ! comment\n" +
+		"a = 1\n" +
+		"b:2\n" +
+		"c 3\n" +
+		"d\n" +
+		"e\\ f = multi \\\n" +
+		"    line \\\\\n" +
+		"g=\\u0442\\uD83D\\uDE00\\q\r\n" +
+		"h=\\\\\\\n" +
+		"  i\n" +
+		"j=end\\"
+	m := map[string]interface{}{}
+	unknown, err := ReadProperties(strings.NewReader(input), m)
+	a.NoError(err)
+	a.Empty(unknown)
+	a.Equal(map[string]interface{}{
+		"a":   "1",
+		"b":   "2",
+		"c":   "3",
+		"d":   "",
+		"e f": "multi line \\",
+		"g":   "т😀q",
+		"h":   "\\i",
+		"j":   "end",
+	}, m)
+
+	for _, input := range []string{"a=1\nb=\\u12\n", "a=1\nb=\\u12x4\n"} {
+		_, err := ReadProperties(strings.NewReader(input), m)
+		a.True(errors.Is(err, ErrBadSyntax), "%q: %v", input, err)
+		a.Contains(err.Error(), "line 2", input)
+	}
+}
@@ -0,0 +1,466 @@
+package goflat
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrBadQuery is returned when a query expression can't be compiled.
+var ErrBadQuery = errors.New("goflat: bad query")
+
+// Expr is a compiled query expression. See CompileExpr.
+// An Expr is safe for concurrent use.
+type Expr struct {
+	root exprNode
+}
+
+// exprNode evaluates a part of an expression. It returns the matching paths and the truth value.
+type exprNode interface {
+	eval(obj interface{}) ([]string, bool)
+}
+
+// CompileExpr parses a query expression. The syntax is:
+//
+//	Items[*].Price > 10             comparison of the selected values with a literal: == != < <= > >=
+//	Status.Error                    existence check: true if the selector matches anything
+//	A > 1 && (B == "x" || !C)       boolean operators and parentheses
+//
+// Selectors are compiled with Compile and the given options. Literals are numbers, quoted strings,
+// true, false and null. Numbers are compared with numeric values, strings with strings, other values
+// are formatted with the formatter set by WithValueFormatter option, or with the default one, and compared as strings.
+// Pointers and interfaces are dereferenced before comparison, null matches nil values.
+//
+// A comparison is true, if any of the selected values matches, and yields the paths of the matching values.
+// && and || combine the paths of their true operands, ! yields no paths.
+func CompileExpr(expr string, opts ...Option) (*Expr, error) {
+	o := makeOptions(opts...)
+	tokens, err := lexQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{expr: expr, tokens: tokens, o: o}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+	return &Expr{root: root}, nil
+}
+
+// MustCompileExpr is like CompileExpr, but panics if the expression can't be compiled.
+func MustCompileExpr(expr string, opts ...Option) *Expr {
+	e, err := CompileExpr(expr, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Eval evaluates the expression against obj. It returns the paths of the matching values without duplicates,
+// and the truth value of the expression.
+func (e *Expr) Eval(obj interface{}) ([]string, bool) {
+	paths, ok := e.root.eval(obj)
+	return dedupPaths(paths), ok
+}
+
+// Match returns the truth value of the expression for obj.
+func (e *Expr) Match(obj interface{}) bool {
+	_, ok := e.root.eval(obj)
+	return ok
+}
+
+// Query evaluates a query expression against obj and returns the paths of the matching values,
+// e.g. Query(obj, `Items[*].Price > 10`) returns the paths of the prices greater than 10.
+// See CompileExpr for the syntax.
+func Query(obj interface{}, expr string, opts ...Option) ([]string, error) {
+	e, err := CompileExpr(expr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	paths, _ := e.Eval(obj)
+	return paths, nil
+}
+
+func dedupPaths(paths []string) []string {
+	seen := make(map[string]struct{}, len(paths))
+	res := paths[:0]
+	for _, path := range paths {
+		if _, found := seen[path]; !found {
+			seen[path] = struct{}{}
+			res = append(res, path)
+		}
+	}
+	return res
+}
+
+type andNode struct {
+	left, right exprNode
+}
+
+func (n *andNode) eval(obj interface{}) ([]string, bool) {
+	left, ok := n.left.eval(obj)
+	if !ok {
+		return nil, false
+	}
+	right, ok := n.right.eval(obj)
+	if !ok {
+		return nil, false
+	}
+	return append(left, right...), true
+}
+
+type orNode struct {
+	left, right exprNode
+}
+
+func (n *orNode) eval(obj interface{}) ([]string, bool) {
+	left, leftOk := n.left.eval(obj)
+	right, rightOk := n.right.eval(obj)
+	return append(left, right...), leftOk || rightOk
+}
+
+type notNode struct {
+	operand exprNode
+}
+
+func (n *notNode) eval(obj interface{}) ([]string, bool) {
+	_, ok := n.operand.eval(obj)
+	return nil, !ok
+}
+
+// matchNode selects values and checks them with the compare function, if it is set.
+type matchNode struct {
+	s   *Selector
+	cmp func(v interface{}) bool
+}
+
+func (n *matchNode) eval(obj interface{}) ([]string, bool) {
+	var paths []string
+	n.s.Select(obj, func(path string, value interface{}) bool {
+		if n.cmp == nil || n.cmp(value) {
+			paths = append(paths, path)
+		}
+		return true
+	})
+	return paths, len(paths) > 0
+}
+
+// literal is a parsed query literal.
+type literal struct {
+	kind  reflect.Kind // Invalid for null, Bool, Float64 for numbers, or String.
+	b     bool
+	i     int64
+	f     float64
+	s     string
+	isInt bool
+}
+
+func (o *options) comparer(op string, lit literal) func(v interface{}) bool {
+	f := o.formatter
+	if f == nil {
+		f = NewValueFormatter()
+	}
+	return func(v interface{}) bool {
+		val := reflect.ValueOf(v)
+		for (val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface) && !val.IsNil() {
+			val = val.Elem()
+		}
+		isNil := !val.IsValid()
+		switch val.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+			isNil = val.IsNil()
+		}
+		if lit.kind == reflect.Invalid || isNil {
+			eq := isNil && lit.kind == reflect.Invalid
+			return (op == "==" && eq) || (op == "!=" && !eq)
+		}
+		kind := val.Kind()
+		switch {
+		case lit.kind == reflect.Bool:
+			if kind != reflect.Bool {
+				return op == "!="
+			}
+			return (op == "==" && val.Bool() == lit.b) || (op == "!=" && val.Bool() != lit.b)
+		case lit.kind == reflect.String:
+			var s string
+			if kind == reflect.String {
+				s = val.String()
+			} else {
+				s = f.format(val)
+			}
+			return compareOrdered(op, strings.Compare(s, lit.s))
+		case kind >= reflect.Int && kind <= reflect.Int64 && lit.isInt:
+			return compareOrdered(op, compareInts(val.Int(), lit.i))
+		case kind >= reflect.Uint && kind <= reflect.Uintptr && lit.isInt:
+			if lit.i < 0 {
+				return compareOrdered(op, 1)
+			}
+			u := uint64(lit.i)
+			switch {
+			case val.Uint() < u:
+				return compareOrdered(op, -1)
+			case val.Uint() > u:
+				return compareOrdered(op, 1)
+			}
+			return compareOrdered(op, 0)
+		case kind >= reflect.Int && kind <= reflect.Int64:
+			return compareOrdered(op, compareFloats(float64(val.Int()), lit.f))
+		case kind >= reflect.Uint && kind <= reflect.Uintptr:
+			return compareOrdered(op, compareFloats(float64(val.Uint()), lit.f))
+		case kind == reflect.Float32 || kind == reflect.Float64:
+			return compareOrdered(op, compareFloats(val.Float(), lit.f))
+		}
+		return op == "!="
+	}
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	case a == b:
+		return 0
+	}
+	// NaN is not equal to anything.
+	return 2
+}
+
+// compareOrdered applies the comparison operator to the result of a three-way comparison.
+func compareOrdered(op string, cmp int) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp == -1
+	case "<=":
+		return cmp == -1 || cmp == 0
+	case ">":
+		return cmp == 1
+	case ">=":
+		return cmp == 1 || cmp == 0
+	}
+	return false
+}
+
+type tokenKind int8
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokCompare
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexQuery splits a query expression into tokens.
+// Words are selectors and literals, '=' inside brackets belongs to a word, e.g. "Items[ID=42].Price".
+func lexQuery(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+		case strings.HasPrefix(expr[i:], "&&"):
+			tokens = append(tokens, token{kind: tokAnd, text: "&&", pos: i})
+		case strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, token{kind: tokOr, text: "||", pos: i})
+		case strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="),
+			strings.HasPrefix(expr[i:], "<="), strings.HasPrefix(expr[i:], ">="):
+			tokens = append(tokens, token{kind: tokCompare, text: expr[i : i+2], pos: i})
+		case c == '<' || c == '>':
+			tokens = append(tokens, token{kind: tokCompare, text: expr[i : i+1], pos: i})
+		case c == '!':
+			tokens = append(tokens, token{kind: tokNot, text: "!", pos: i})
+		case c == '"':
+			quoted, err := strconv.QuotedPrefix(expr[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: bad string at %d", ErrBadQuery, expr, i)
+			}
+			s, _ := strconv.Unquote(quoted)
+			tokens = append(tokens, token{kind: tokString, text: s, pos: i})
+			i += len(quoted)
+			continue
+		default:
+			start, depth := i, 0
+		loop:
+			for ; i < len(expr); i++ {
+				switch expr[i] {
+				case '[':
+					depth++
+				case ']':
+					depth--
+				case ' ', '\t', '\n', '\r', '(', ')', '&', '|', '!', '<', '>', '"':
+					if depth == 0 {
+						break loop
+					}
+				case '=':
+					if depth == 0 {
+						break loop
+					}
+				}
+			}
+			if i == start {
+				return nil, fmt.Errorf("%w: %q: unexpected %q at %d", ErrBadQuery, expr, expr[i], i)
+			}
+			tokens = append(tokens, token{kind: tokWord, text: expr[start:i], pos: start})
+			continue
+		}
+		i += len(tokens[len(tokens)-1].text)
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+type queryParser struct {
+	expr   string
+	tokens []token
+	pos    int
+	o      *options
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) errorf(tok token, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %q: %s at %d", ErrBadQuery, p.expr, fmt.Sprintf(format, args...), tok.pos)
+}
+
+func (p *queryParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (exprNode, error) {
+	switch tok := p.next(); tok.kind {
+	case tokNot:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "missing )")
+		}
+		return node, nil
+	case tokWord:
+		s, err := compile(tok.text, p.o)
+		if err != nil {
+			return nil, p.errorf(tok, "%v", err)
+		}
+		node := &matchNode{s: s}
+		if p.peek().kind != tokCompare {
+			return node, nil
+		}
+		op := p.next().text
+		lit, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		node.cmp = p.o.comparer(op, lit)
+		return node, nil
+	case tokEOF:
+		return nil, p.errorf(tok, "unexpected end")
+	default:
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+}
+
+func (p *queryParser) parseLiteral() (literal, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return literal{kind: reflect.String, s: tok.text}, nil
+	case tokWord:
+		switch tok.text {
+		case "null":
+			return literal{kind: reflect.Invalid}, nil
+		case "true", "false":
+			return literal{kind: reflect.Bool, b: tok.text == "true"}, nil
+		}
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return literal{}, p.errorf(tok, "bad literal %q", tok.text)
+		}
+		lit := literal{kind: reflect.Float64, f: f}
+		if i, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			lit.i, lit.isInt = i, true
+		}
+		return lit, nil
+	}
+	return literal{}, p.errorf(tok, "expected a literal")
+}
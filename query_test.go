@@ -0,0 +1,84 @@
+package goflat
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	a := assert.New(t)
+	type item struct {
+		Name  string
+		Price float64
+		Qty   uint
+		Tags  []string
+		Note  *string
+	}
+	note := "sale"
+	obj := struct {
+		Items  []item
+		Count  int
+		Active bool
+		Error  error
+	}{
+		Items: []item{
+			{Name: "a", Price: 5, Qty: 1},
+			{Name: "b", Price: 15, Qty: 0, Note: &note},
+			{Name: "c d", Price: 25.5, Qty: 3, Tags: []string{"x"}},
+		},
+		Count:  3,
+		Active: true,
+	}
+	tests := []struct {
+		expr  string
+		paths []string
+		ok    bool
+	}{
+		{expr: `Items[*].Price > 10`, paths: []string{"Items.1.Price", "Items.2.Price"}, ok: true},
+		{expr: `Items.*.Price>=25.5`, paths: []string{"Items.2.Price"}, ok: true},
+		{expr: `Items[*].Price < 0`, ok: false},
+		{expr: `Items[*].Qty == 0`, paths: []string{"Items.1.Qty"}, ok: true},
+		{expr: `Items[*].Qty > -1`, paths: []string{"Items.0.Qty", "Items.1.Qty", "Items.2.Qty"}, ok: true},
+		{expr: `Items[*].Name == "c d"`, paths: []string{"Items.2.Name"}, ok: true},
+		{expr: `Items[*].Name != "a" && Count <= 3`, paths: []string{"Items.1.Name", "Items.2.Name", "Count"}, ok: true},
+		{expr: `Count > 5 && Active`, ok: false},
+		{expr: `Count > 5 || Active == true`, paths: []string{"Active"}, ok: true},
+		{expr: `!(Count > 5)`, ok: true},
+		{expr: `!Active`, ok: false},
+		{expr: `Items.*.Tags.0`, paths: []string{"Items.2.Tags.0"}, ok: true},
+		{expr: `Items.*.Note == "sale"`, paths: []string{"Items.1.Note"}, ok: true},
+		{expr: `Items.*.Note == null`, paths: []string{"Items.0.Note", "Items.2.Note"}, ok: true},
+		{expr: `Error == null`, paths: []string{"Error"}, ok: true},
+		{expr: `Count == "3"`, paths: []string{"Count"}, ok: true},
+		{expr: `Active == 1`, ok: false},
+		{expr: `(Count == 3 || Count == 3) && Count == 3`, paths: []string{"Count"}, ok: true},
+	}
+	for _, test := range tests {
+		e, err := CompileExpr(test.expr)
+		if !a.NoError(err, test.expr) {
+			continue
+		}
+		paths, ok := e.Eval(obj)
+		a.Equal(test.ok, ok, test.expr)
+		a.Equal(test.ok, e.Match(&obj), test.expr)
+		if len(test.paths) == 0 {
+			a.Empty(paths, test.expr)
+		} else {
+			a.Equal(test.paths, paths, test.expr)
+		}
+	}
+	paths, err := Query(obj, `Items[Name=b].Price > 10`, SliceKeyField(reflect.TypeOf(item{}), "Name"))
+	a.NoError(err)
+	a.Equal([]string{"Items[Name=b].Price"}, paths)
+}
+
+func TestQueryErrors(t *testing.T) {
+	a := assert.New(t)
+	for _, expr := range []string{``, `A >`, `A > B`, `(A`, `A)`, `A && || B`, `A == "x`, `> 1`, `A..B > 1`, `A = 1`} {
+		_, err := Query(nil, expr)
+		a.True(errors.Is(err, ErrBadQuery), "%s: %v", expr, err)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package goflat
+
+// raceEnabled is true, if the tests are built with the race detector, which makes sync.Pool drop items randomly.
+const raceEnabled = true
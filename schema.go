@@ -0,0 +1,67 @@
+package goflat
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SchemaField describes a path, which Flatten may produce for a value of a type.
+type SchemaField struct {
+	// Path is a flattened key. Map keys and slice elements are represented by Wildcard, see WalkType.
+	Path string
+	// Type is the type of the value. For recursive fields it is the type, which is not expanded again.
+	Type reflect.Type
+	// Tag is the tag of the innermost struct field on the path.
+	Tag reflect.StructTag
+	// Optional is true, if the value may be missing, i.e. it is a pointer, a map, a slice or an interface,
+	// or it is contained in one.
+	Optional bool
+	// Recursive marks a value of a type, which is already being expanded at the Ref path.
+	// The paths under Path repeat the paths under Ref.
+	Recursive bool
+	// Ref is the path, where the type of a recursive field is expanded.
+	Ref string
+}
+
+// Schema returns all the paths, which Flatten may produce for a value of typ, in the order Walk visits them.
+// Options, which affect naming and expansion, like ExpandUnexported or WithPointerFllowPolicy, are honoured.
+// Instead of expanding a recursive type again, a field marked as Recursive is returned.
+func Schema(typ reflect.Type, opts ...Option) []SchemaField {
+	if typ == nil {
+		return nil
+	}
+	return makeOptions(opts...).schema(typ)
+}
+
+func (o *options) schema(typ reflect.Type) []SchemaField {
+	var fields []SchemaField
+	var tw *typeWalker
+	field := func(path []string, typ reflect.Type) SchemaField {
+		return SchemaField{
+			Path:     strings.Join(path, o.delimeter),
+			Type:     typ,
+			Tag:      tw.tag,
+			Optional: tw.optional > 0 || isOptional(typ.Kind()),
+		}
+	}
+	tw = newTypeWalker(func(path []string, typ reflect.Type) bool {
+		fields = append(fields, field(path, typ))
+		return true
+	}, o)
+	tw.recursion = func(path []string, typ reflect.Type, ref string) bool {
+		f := field(path, typ)
+		f.Recursive, f.Ref = true, ref
+		fields = append(fields, f)
+		return true
+	}
+	tw.visit(typ, nil)
+	return fields
+}
+
+func isOptional(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package goflat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaConfig struct {
+	Name    string            `goflat:"name" default:"app"`
+	Port    *int              `goflat:"port"`
+	Labels  map[string]string `goflat:"labels"`
+	Servers []schemaServer    `goflat:"servers,key=Host"`
+	Limits  [2]int
+	Root    schemaNode
+	secret  string
+}
+
+type schemaServer struct {
+	Host    string `goflat:"host"`
+	Weights []float64
+}
+
+type schemaNode struct {
+	Value    int
+	Children map[string]schemaNode
+	Next     *schemaNode
+}
+
+func TestSchema(t *testing.T) {
+	a := assert.New(t)
+	strType, intType := reflect.TypeOf(""), reflect.TypeOf(0)
+	nodeType := reflect.TypeOf(schemaNode{})
+	a.Equal([]SchemaField{
+		{Path: "name", Type: strType, Tag: `goflat:"name" default:"app"`},
+		{Path: "port", Type: reflect.TypeOf((*int)(nil)), Tag: `goflat:"port"`, Optional: true},
+		{Path: "labels.*", Type: strType, Tag: `goflat:"labels"`, Optional: true},
+		{Path: "servers[Host=*].host", Type: strType, Tag: `goflat:"host"`, Optional: true},
+		{Path: "servers[Host=*].Weights.*", Type: reflect.TypeOf(0.0), Optional: true},
+		{Path: "Limits.*", Type: intType},
+		{Path: "Root.Value", Type: intType},
+		{Path: "Root.Children.*", Type: nodeType, Optional: true, Recursive: true, Ref: "Root"},
+		{Path: "Root.Next", Type: nodeType, Optional: true, Recursive: true, Ref: "Root"},
+	}, Schema(reflect.TypeOf(schemaConfig{})))
+
+	fields := Schema(reflect.TypeOf(&schemaConfig{}),
+		ExpandUnexported(true),
+		WithDelimeter("/"),
+		WithPointerFllowPolicy(PointerPolicyJustValue),
+		CollapsePrimitiveSlices(CollapseJoin, ","),
+	)
+	paths := make(map[string]SchemaField, len(fields))
+	for _, f := range fields {
+		paths[f.Path] = f
+	}
+	a.Equal(SchemaField{Path: "port", Type: intType, Tag: `goflat:"port"`, Optional: true}, paths["port"])
+	a.Equal(SchemaField{Path: "servers[Host=*]/Weights", Type: strType, Optional: true}, paths["servers[Host=*]/Weights"])
+	a.Equal(SchemaField{Path: "Limits", Type: strType}, paths["Limits"])
+	a.Equal(SchemaField{Path: "secret", Type: strType}, paths["secret"])
+	a.Equal(SchemaField{Path: "Root/Next", Type: nodeType, Optional: true, Recursive: true, Ref: "Root"}, paths["Root/Next"])
+
+	a.Equal([]SchemaField{{Type: intType}}, Schema(intType))
+	a.Nil(Schema(nil))
+}
@@ -0,0 +1,235 @@
+package goflat
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrBadPattern is returned when a selector pattern can't be compiled.
+var ErrBadPattern = errors.New("goflat: bad pattern")
+
+// Selector selects values from objects by a path pattern. See Compile.
+// A Selector is safe for concurrent use.
+type Selector struct {
+	o       *options
+	pattern []pathElem
+	// plans caches matching struct fields per type and pattern element, see fields.
+	plans sync.Map
+}
+
+// planKey identifies struct fields, which match the pattern element at depth.
+type planKey struct {
+	typ   reflect.Type
+	depth int
+}
+
+// Compile parses a path pattern, like "Nodes.*.Stats.CPU", into a Selector.
+// The pattern consists of elements separated by the delimeter. "*" matches any struct field, map key or slice element,
+// "Items[*]" is the same as "Items.*". Other elements are resolved with the same naming rules as Walk uses,
+// e.g. "Items[ID=42]" selects an element of a slice addressed by a key field.
+// The options must match those used for flattening.
+func Compile(pattern string, opts ...Option) (*Selector, error) {
+	return compile(pattern, makeOptions(opts...))
+}
+
+func compile(pattern string, o *options) (*Selector, error) {
+	var elems []pathElem
+	for _, elem := range parsePath(pattern, o.delimeter) {
+		if name := strings.TrimSuffix(elem.name, "[*]"); name != elem.name && !elem.keyed {
+			if name != "" {
+				elems = append(elems, pathElem{name: name})
+			}
+			elem = pathElem{name: "*"}
+		}
+		if elem.name == "" {
+			return nil, fmt.Errorf("%w: %q: empty element", ErrBadPattern, pattern)
+		}
+		elems = append(elems, elem)
+	}
+	return &Selector{o: o, pattern: elems}, nil
+}
+
+// MustCompile is like Compile, but panics if the pattern can't be compiled.
+func MustCompile(pattern string, opts ...Option) *Selector {
+	s, err := Compile(pattern, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Select calls fn for every value in obj, whose path matches the pattern, until fn returns false.
+// Only the parts of obj, which may match the pattern, are visited.
+// Values are reported as Get returns them, so a pattern may select containers as well as leaf values.
+// Struct fields are visited in declaration order, slices in index order, map keys are sorted if SortMapKeys option is set.
+func (s *Selector) Select(obj interface{}, fn func(path string, value interface{}) bool) {
+	s.selectAt(reflect.ValueOf(obj), make([]string, 0, len(s.pattern)), 0, "", fn)
+}
+
+// SelectAll returns all the path-value pairs selected from obj.
+func (s *Selector) SelectAll(obj interface{}) []KV {
+	var kvs []KV
+	s.Select(obj, func(path string, value interface{}) bool {
+		kvs = append(kvs, KV{Path: path, Value: value})
+		return true
+	})
+	return kvs
+}
+
+// selectAt matches the pattern elements starting at depth against val. path is the path of val.
+// key is a key field name, if val is a slice addressed by key field.
+func (s *Selector) selectAt(val reflect.Value, path []string, depth int, key string, fn func(string, interface{}) bool) (cont bool) {
+	if depth == len(s.pattern) {
+		return fn(strings.Join(path, s.o.delimeter), s.value(val, path))
+	}
+	for val.Kind() == reflect.Interface || val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return true
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() || (!s.o.expandBytes && isBytes(val.Type())) {
+		return true
+	}
+	elem := s.pattern[depth]
+	switch val.Kind() {
+	case reflect.Struct:
+		if elem.keyed {
+			return true
+		}
+		for _, field := range s.fields(val.Type(), depth) {
+			if !s.selectAt(val.Field(field.index), append(path, field.name), depth+1, field.key, fn) {
+				return false
+			}
+		}
+	case reflect.Map:
+		return s.selectMap(val, path, depth, fn)
+	case reflect.Slice, reflect.Array:
+		if key == "" {
+			key = s.o.sliceKeys[val.Type().Elem()]
+		}
+		return s.selectSliceOrArray(val, path, depth, key, fn)
+	}
+	return true
+}
+
+// value returns a selected value. Collapsed slices and sets are reported as Walk reports them,
+// other values are reported as Get returns them.
+func (s *Selector) value(val reflect.Value, path []string) interface{} {
+	val = s.o.followPointer(val)
+	elem := val
+	for elem.Kind() == reflect.Interface || (elem.Kind() == reflect.Pointer && !elem.IsNil()) {
+		elem = elem.Elem()
+	}
+	switch elem.Kind() {
+	case reflect.Slice, reflect.Array:
+		if !(elem.Kind() == reflect.Slice && elem.IsNil()) && s.o.shouldCollapse(elem.Type(), path) {
+			return s.o.collapsedValue(elem)
+		}
+	case reflect.Map:
+		if s.o.setMode == SetAsValue && isSet(elem.Type()) {
+			return setMembers(elem).Interface()
+		}
+	}
+	return valueInterface(val)
+}
+
+// fields returns the fields of the struct type, which match the pattern element at depth.
+func (s *Selector) fields(typ reflect.Type, depth int) []structField {
+	pk := planKey{typ: typ, depth: depth}
+	if fields, found := s.plans.Load(pk); found {
+		return fields.([]structField)
+	}
+	var fields []structField
+	for _, field := range s.o.structFields(typ) {
+		if name := s.pattern[depth].name; name == "*" || name == field.name {
+			fields = append(fields, field)
+		}
+	}
+	s.plans.Store(pk, fields)
+	return fields
+}
+
+func (s *Selector) selectMap(val reflect.Value, path []string, depth int, fn func(string, interface{}) bool) (cont bool) {
+	elem := s.pattern[depth]
+	if s.o.setMode != SetNone && isSet(val.Type()) {
+		if s.o.setMode == SetAsValue {
+			return true
+		}
+		return s.selectSliceOrArray(setMembers(val), path, depth, "", fn)
+	}
+	if val.Type().Key().Kind() != reflect.String || elem.keyed {
+		return true
+	}
+	if elem.name != "*" {
+		v := val.MapIndex(reflect.ValueOf(elem.name).Convert(val.Type().Key()))
+		if !v.IsValid() {
+			return true
+		}
+		return s.selectAt(v, append(path, elem.name), depth+1, "", fn)
+	}
+	keys := val.MapKeys()
+	if s.o.sortMapKeys {
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+	}
+	for _, k := range keys {
+		if !s.selectAt(val.MapIndex(k), append(path, k.String()), depth+1, "", fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Selector) selectSliceOrArray(val reflect.Value, path []string, depth int, key string, fn func(string, interface{}) bool) (cont bool) {
+	if s.o.shouldCollapse(val.Type(), path) {
+		return true
+	}
+	elem := s.pattern[depth]
+	if elem.name == "*" {
+		if key != "" {
+			if segments, err := s.o.keySegments(val, path, key); err == nil {
+				parent := s.o.keyedParent(path)
+				for i, segment := range segments {
+					if !s.selectAt(val.Index(i), append(parent, segment), depth+1, "", fn) {
+						return false
+					}
+				}
+				return true
+			}
+		}
+		for i := 0; i < val.Len(); i++ {
+			if !s.selectAt(val.Index(i), append(path, s.o.indexSegment(i, key)), depth+1, "", fn) {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case elem.keyed:
+		if idx := s.o.findKeyIndex(val, elem.keyField, elem.keyValue, false); idx >= 0 {
+			if s.o.keyStyle == KeyStyleSegment {
+				return s.selectAt(val.Index(idx), append(path, elem.keyValue), depth+1, "", fn)
+			}
+			if len(path) == 0 {
+				return s.selectAt(val.Index(idx), append(path, elem.name), depth+1, "", fn)
+			}
+			parent := path[:len(path)-1]
+			return s.selectAt(val.Index(idx), append(parent, path[len(path)-1]+elem.name), depth+1, "", fn)
+		}
+	case key != "" && s.o.keyStyle == KeyStyleSegment && !strings.HasPrefix(elem.name, IndexPrefix):
+		if idx := s.o.findKeyIndex(val, key, elem.name, false); idx >= 0 {
+			return s.selectAt(val.Index(idx), append(path, elem.name), depth+1, "", fn)
+		}
+	default:
+		if idx, ok := s.o.parseIndex(elem.name, key); ok && idx < val.Len() {
+			return s.selectAt(val.Index(idx), append(path, elem.name), depth+1, "", fn)
+		}
+	}
+	return true
+}
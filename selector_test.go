@@ -0,0 +1,99 @@
+package goflat
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/avdva/goflat/testpkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelector(t *testing.T) {
+	a := assert.New(t)
+	obj := testpkg.NewTestStruct()
+	flat := Flatten(obj)
+	for _, pattern := range []string{"A", "*", "S.*", "*.*", "Slice.*", "Slice[*]", "Array.1", "M.*", "S.M.k", "Iface.Val", "*.Val"} {
+		exp := FlatMap{}
+		for path, value := range flat {
+			if matchPath(strings.ReplaceAll(pattern, "[*]", ".*"), strings.Split(path, "."), ".") {
+				exp[path] = value
+			}
+		}
+		got := FlatMap{}
+		for _, kv := range MustCompile(pattern).SelectAll(obj) {
+			// containers are selected as well, but Flatten doesn't report them.
+			if flat.Has(kv.Path) {
+				got[kv.Path] = kv.Value
+			}
+		}
+		a.Equal(exp, got, pattern)
+	}
+	a.Empty(MustCompile("None").SelectAll(obj))
+	a.Empty(MustCompile("Slice.5").SelectAll(obj))
+	a.Empty(MustCompile("A.B").SelectAll(obj))
+	_, err := Compile("A..B")
+	a.True(errors.Is(err, ErrBadPattern))
+}
+
+func TestSelectorContainers(t *testing.T) {
+	a := assert.New(t)
+	type stats struct {
+		CPU float64
+		Mem int
+	}
+	type node struct {
+		Stats *stats
+	}
+	obj := struct {
+		Nodes map[string]node
+		Items []keyItem `goflat:",key=ID"`
+	}{
+		Nodes: map[string]node{
+			"a": {Stats: &stats{CPU: 0.5, Mem: 1}},
+			"b": {Stats: &stats{CPU: 0.25, Mem: 2}},
+			"c": {},
+		},
+		Items: []keyItem{{ID: 1, Name: "x"}, {ID: 2, Name: "y"}},
+	}
+	s := MustCompile("Nodes.*.Stats.CPU", SortMapKeys(true))
+	a.Equal([]KV{{Path: "Nodes.a.Stats.CPU", Value: 0.5}, {Path: "Nodes.b.Stats.CPU", Value: 0.25}}, s.SelectAll(obj))
+	a.Equal([]KV{{Path: "Nodes.a.Stats.CPU", Value: 0.5}, {Path: "Nodes.b.Stats.CPU", Value: 0.25}}, s.SelectAll(&obj))
+	a.Equal([]KV{{Path: "Nodes.a.Stats", Value: stats{CPU: 0.5, Mem: 1}}}, MustCompile("Nodes.a.Stats").SelectAll(obj))
+	a.Equal([]KV{
+		{Path: "Items[ID=1].Name", Value: "x"},
+		{Path: "Items[ID=2].Name", Value: "y"},
+	}, MustCompile("Items[*].Name").SelectAll(obj))
+	a.Equal([]KV{{Path: "Items[ID=2].Name", Value: "y"}}, MustCompile("Items[ID=2].Name").SelectAll(obj))
+	a.Equal([]KV{{Path: "Items/2/Name", Value: "y"}},
+		MustCompile("Items/2/Name", WithKeyStyle(KeyStyleSegment), WithDelimeter("/")).SelectAll(obj))
+	var count int
+	s.Select(obj, func(path string, value interface{}) bool {
+		count++
+		return false
+	})
+	a.Equal(1, count)
+}
+
+func TestSelectorOptions(t *testing.T) {
+	a := assert.New(t)
+	obj := struct {
+		Tags  []string
+		Set   map[string]struct{}
+		Bytes []byte
+	}{
+		Tags:  []string{"a", "b"},
+		Set:   map[string]struct{}{"y": {}, "x": {}},
+		Bytes: []byte{1, 2},
+	}
+	a.Equal([]KV{{Path: "Tags.1", Value: "b"}}, MustCompile("Tags.1").SelectAll(obj))
+	a.Empty(MustCompile("Tags.1", CollapsePrimitiveSlices(CollapseJoin, ",")).SelectAll(obj))
+	a.Equal([]KV{{Path: "Tags", Value: "a,b"}}, MustCompile("Tags", CollapsePrimitiveSlices(CollapseJoin, ",")).SelectAll(obj))
+	a.Equal([]KV{{Path: "Set", Value: []string{"x", "y"}}}, MustCompile("Set", WithSetMode(SetAsValue)).SelectAll(obj))
+	a.Equal([]KV{{Path: "Set.0", Value: "x"}, {Path: "Set.1", Value: "y"}}, MustCompile("Set.*", WithSetMode(SetAsList)).SelectAll(obj))
+	a.Empty(MustCompile("Bytes.0").SelectAll(obj))
+	a.Equal([]KV{{Path: "Bytes.0", Value: uint8(1)}}, MustCompile("Bytes.0", ExpandBytes(true)).SelectAll(obj))
+	a.Equal(reflect.TypeOf(obj.Bytes), reflect.TypeOf(MustCompile("Bytes").SelectAll(obj)[0].Value))
+}
@@ -0,0 +1,93 @@
+// Package gentest contains types with WalkGoflat methods generated by goflat-gen.
+package gentest
+
+import "time"
+
+//go:generate go run ../../cmd/goflat-gen
+
+// Item is an element of a slice addressed by a key field.
+//
+//goflat:generate
+type Item struct {
+	ID    int
+	Price float64
+	Tags  []string
+}
+
+// Sample has fields of all the kinds goflat-gen handles differently.
+//
+//goflat:generate
+type Sample struct {
+	Int      int
+	Uint8    uint8
+	Float    float32
+	Complex  complex128
+	Bool     bool
+	String   string `goflat:"str"`
+	Skipped  string `goflat:"-"`
+	Duration time.Duration
+	Time     time.Time
+	Bytes    []byte
+	Ptr      *int
+	Items    []Item `goflat:",key=ID"`
+	ItemPtrs []*Item
+	Array    [2]Item
+	Matrix   [][]string
+	Map      map[string]Item
+	PtrMap   map[string]*Item
+	Iface    interface{}
+	Next     *Sample
+	Nested   struct {
+		A int
+	}
+	Embedded
+	private    string
+	privatePtr *int
+	privateMap map[string]int
+}
+
+// Wrapper embeds a type with generated methods and has its own ones.
+//
+//goflat:generate
+type Wrapper struct {
+	*Item
+	Extra int
+}
+
+// Embedded is embedded into Sample.
+type Embedded struct {
+	E string
+}
+
+// NewSample returns a Sample with all the fields set.
+func NewSample() *Sample {
+	i := 42
+	s := &Sample{
+		Int:      -1,
+		Uint8:    2,
+		Float:    1.5,
+		Complex:  complex(1, 2),
+		Bool:     true,
+		String:   "str",
+		Skipped:  "skipped",
+		Duration: time.Second,
+		Time:     time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Bytes:    []byte("bytes"),
+		Ptr:      &i,
+		Items:    []Item{{ID: 1, Price: 1.5, Tags: []string{"a"}}, {ID: 2, Price: 2.5}},
+		ItemPtrs: []*Item{{ID: 3}, nil},
+		Array:    [2]Item{{ID: 6}},
+		Matrix:   [][]string{{"x", "y"}, nil},
+		Map:      map[string]Item{"k": {ID: 4}},
+		PtrMap:   map[string]*Item{"k": {ID: 7}},
+		Iface:    Item{ID: 5},
+		Embedded: Embedded{E: "e"},
+		private:  "private",
+
+		privatePtr: &i,
+		privateMap: map[string]int{"k": 1},
+	}
+	s.Nested.A = 7
+	s.Next = &Sample{Int: 1, Next: s}
+	return s
+}
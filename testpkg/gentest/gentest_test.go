@@ -0,0 +1,134 @@
+package gentest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/avdva/goflat"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var _ goflat.Walker = Sample{}
+
+// reflectSample and reflectItem have the same fields, but no generated methods.
+type (
+	reflectSample Sample
+	reflectItem   Item
+)
+
+func TestGenerated(t *testing.T) {
+	a := assert.New(t)
+	sample := NewSample()
+	optsList := [][]goflat.Option{
+		nil,
+		{goflat.ExpandUnexported(true)},
+		{goflat.ExpandUnexported(true), goflat.AddNilFields(true), goflat.AddNilContainers(true)},
+		{goflat.WithPointerFllowPolicy(goflat.PointerPolicyBoth), goflat.SortMapKeys(true)},
+		{goflat.WithPointerFllowPolicy(goflat.PointerPolicyJustValue), goflat.WithKeyStyle(goflat.KeyStyleSegment)},
+		{goflat.ExpandBytes(true), goflat.CollapsePrimitiveSlices(goflat.CollapseJoin, ",")},
+	}
+	for _, opts := range optsList {
+		a.Equal(withoutRoot(goflat.FlattenOrdered((*reflectSample)(sample), opts...)), withoutRoot(goflat.FlattenOrdered(sample, opts...)))
+		a.Equal(goflat.FlattenOrdered(reflectSample(*sample), opts...), goflat.FlattenOrdered(*sample, opts...))
+		a.Equal(goflat.FlattenStrings((*reflectSample)(sample), opts...), goflat.FlattenStrings(sample, opts...))
+		item := sample.Items[0]
+		a.Equal(goflat.FlattenOrdered(reflectItem(item), opts...), goflat.FlattenOrdered(item, opts...))
+	}
+	exp, got := goflat.FlatMap{}, goflat.FlatMap{}
+	goflat.WalkReflect((*reflectSample)(sample), func(path []string, value reflect.Value) bool {
+		exp[strings.Join(path, ".")] = value.Interface()
+		return true
+	})
+	goflat.WalkReflect(sample, func(path []string, value reflect.Value) bool {
+		got[strings.Join(path, ".")] = value.Interface()
+		return true
+	})
+	a.Equal(exp, got)
+	a.Contains(got, "Items[ID=1].Tags.0")
+	a.Contains(got, "Next.Int")
+}
+
+// withoutRoot removes the root pointer, reported with PointerPolicyBoth, as its type differs.
+func withoutRoot(kvs []goflat.KV) []goflat.KV {
+	if len(kvs) > 0 && kvs[0].Path == "" {
+		return kvs[1:]
+	}
+	return kvs
+}
+
+func TestGeneratedStop(t *testing.T) {
+	sample := NewSample()
+	var total int
+	goflat.Walk(sample, func([]string, interface{}) bool {
+		total++
+		return true
+	}, goflat.ExpandUnexported(true))
+	for i := 0; i < total; i++ {
+		var current int
+		goflat.Walk(sample, func([]string, interface{}) bool {
+			current++
+			return current <= i
+		}, goflat.ExpandUnexported(true))
+		assert.Equal(t, i+1, current)
+	}
+}
+
+func TestGeneratedEmbedded(t *testing.T) {
+	// outer gets the methods of Item promoted, but they must not be used for its own fields.
+	type outer struct {
+		Item
+		Extra int
+	}
+	obj := outer{Item: Item{ID: 1}, Extra: 5}
+	assert.Equal(t, []goflat.KV{
+		{Path: "Item.ID", Value: 1},
+		{Path: "Item.Price", Value: float64(0)},
+		{Path: "Extra", Value: 5},
+	}, goflat.FlattenOrdered(obj))
+}
+
+func TestGeneratedEmbeddedPointer(t *testing.T) {
+	a := assert.New(t)
+	type outer struct {
+		*Item
+		Extra int
+	}
+	a.Equal(goflat.FlatMap{"Item": (*Item)(nil), "Extra": 5}, goflat.Flatten(outer{Extra: 5}))
+	a.Equal(goflat.FlatMap{"Item.ID": 1, "Item.Price": float64(0), "Extra": 5},
+		goflat.Flatten(outer{Item: &Item{ID: 1}, Extra: 5}))
+	a.Equal(goflat.FlatMap{"Item": (*Item)(nil), "Extra": 5}, goflat.Flatten(Wrapper{Extra: 5}))
+	a.Equal(goflat.FlatMap{"Item.ID": 1, "Item.Price": float64(0), "Extra": 5},
+		goflat.Flatten(&Wrapper{Item: &Item{ID: 1}, Extra: 5}))
+}
+
+// BenchmarkGenerated compares walking the types with generated methods and the same types with reflection.
+func BenchmarkGenerated(b *testing.B) {
+	item := Item{ID: 1, Price: 1.5, Tags: []string{"a", "b", "c"}}
+	sample := NewSample()
+	cb := func([]string, interface{}) bool { return true }
+	benchmarks := []struct {
+		name string
+		obj  interface{}
+	}{
+		{name: "Item/generated", obj: item},
+		{name: "Item/reflect", obj: reflectItem(item)},
+		{name: "Sample/generated", obj: sample},
+		{name: "Sample/reflect", obj: (*reflectSample)(sample)},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				goflat.Walk(bm.obj, cb)
+			}
+		})
+		b.Run(bm.name+"/Flatten", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				goflat.Flatten(bm.obj)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+// Code generated by goflat-gen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"strconv"
+
+	"github.com/avdva/goflat"
+)
+
+// GoflatGenerated marks Item as having its own WalkGoflat method, see goflat.GeneratedMarker.
+func (Item) GoflatGenerated(*Item) {}
+
+// WalkGoflat visits the fields of Item for goflat.Walk.
+func (v Item) WalkGoflat(e goflat.Emitter) bool {
+	direct := e.Direct()
+	if !e.Emit("ID", v.ID) {
+		return false
+	}
+	if !e.Emit("Price", v.Price) {
+		return false
+	}
+	if !direct {
+		if !e.Walk("Tags", "", v.Tags) {
+			return false
+		}
+	} else {
+		if v.Tags == nil {
+			if !e.EmitNil("Tags") {
+				return false
+			}
+		} else if len(v.Tags) > 0 && e.Enter("Tags", &v.Tags[0]) {
+			for i0 := range v.Tags {
+				if !e.Emit(strconv.Itoa(i0), v.Tags[i0]) {
+					return false
+				}
+			}
+			e.Leave(&v.Tags[0])
+		}
+	}
+	return true
+}
+
+// GoflatGenerated marks Sample as having its own WalkGoflat method, see goflat.GeneratedMarker.
+func (Sample) GoflatGenerated(*Sample) {}
+
+// WalkGoflat visits the fields of Sample for goflat.Walk.
+func (v Sample) WalkGoflat(e goflat.Emitter) bool {
+	direct := e.Direct()
+	var parent interface{}
+	if !e.Emit("Int", v.Int) {
+		return false
+	}
+	if !e.Emit("Uint8", v.Uint8) {
+		return false
+	}
+	if !e.Emit("Float", v.Float) {
+		return false
+	}
+	if !e.Emit("Complex", v.Complex) {
+		return false
+	}
+	if !e.Emit("Bool", v.Bool) {
+		return false
+	}
+	if !e.Emit("str", v.String) {
+		return false
+	}
+	if !e.Walk("Duration", "", v.Duration) {
+		return false
+	}
+	if !e.Walk("Time", "", v.Time) {
+		return false
+	}
+	if !direct {
+		if !e.Walk("Bytes", "", v.Bytes) {
+			return false
+		}
+	} else {
+		if v.Bytes == nil {
+			if !e.EmitNil("Bytes") {
+				return false
+			}
+		} else if !e.Emit("Bytes", v.Bytes) {
+			return false
+		}
+	}
+	if !e.Walk("Ptr", "", v.Ptr) {
+		return false
+	}
+	if !e.Walk("Items", "ID", v.Items) {
+		return false
+	}
+	if !direct {
+		if !e.Walk("ItemPtrs", "", v.ItemPtrs) {
+			return false
+		}
+	} else {
+		if v.ItemPtrs == nil {
+			if !e.EmitNil("ItemPtrs") {
+				return false
+			}
+		} else if len(v.ItemPtrs) > 0 && e.Enter("ItemPtrs", &v.ItemPtrs[0]) {
+			for i0 := range v.ItemPtrs {
+				if v.ItemPtrs[i0] == nil {
+					if !e.Emit(strconv.Itoa(i0), v.ItemPtrs[i0]) {
+						return false
+					}
+				} else if e.Enter(strconv.Itoa(i0), v.ItemPtrs[i0]) {
+					if !v.ItemPtrs[i0].WalkGoflat(e) {
+						return false
+					}
+					e.Leave(v.ItemPtrs[i0])
+				}
+			}
+			e.Leave(&v.ItemPtrs[0])
+		}
+	}
+	if !direct {
+		if !e.Walk("Array", "", v.Array) {
+			return false
+		}
+	} else {
+		e.Enter("Array", nil)
+		for i0 := range v.Array {
+			e.Enter(strconv.Itoa(i0), nil)
+			if !v.Array[i0].WalkGoflat(e) {
+				return false
+			}
+			e.Leave(nil)
+		}
+		e.Leave(nil)
+	}
+	if !direct {
+		if !e.Walk("Matrix", "", v.Matrix) {
+			return false
+		}
+	} else {
+		if v.Matrix == nil {
+			if !e.EmitNil("Matrix") {
+				return false
+			}
+		} else if len(v.Matrix) > 0 && e.Enter("Matrix", &v.Matrix[0]) {
+			for i0 := range v.Matrix {
+				if v.Matrix[i0] == nil {
+					if !e.EmitNil(strconv.Itoa(i0)) {
+						return false
+					}
+				} else if len(v.Matrix[i0]) > 0 && e.Enter(strconv.Itoa(i0), &v.Matrix[i0][0]) {
+					for i1 := range v.Matrix[i0] {
+						if !e.Emit(strconv.Itoa(i1), v.Matrix[i0][i1]) {
+							return false
+						}
+					}
+					e.Leave(&v.Matrix[i0][0])
+				}
+			}
+			e.Leave(&v.Matrix[0])
+		}
+	}
+	if !direct {
+		if !e.Walk("Map", "", v.Map) {
+			return false
+		}
+	} else {
+		if v.Map == nil {
+			if !e.EmitNil("Map") {
+				return false
+			}
+		} else if e.Enter("Map", v.Map) {
+			for k0, x0 := range v.Map {
+				e.Enter(k0, nil)
+				if !x0.WalkGoflat(e) {
+					return false
+				}
+				e.Leave(nil)
+			}
+			e.Leave(v.Map)
+		}
+	}
+	if !direct {
+		if !e.Walk("PtrMap", "", v.PtrMap) {
+			return false
+		}
+	} else {
+		if v.PtrMap == nil {
+			if !e.EmitNil("PtrMap") {
+				return false
+			}
+		} else if e.Enter("PtrMap", v.PtrMap) {
+			for k0, x0 := range v.PtrMap {
+				if x0 == nil {
+					if !e.Emit(k0, x0) {
+						return false
+					}
+				} else if e.Enter(k0, x0) {
+					if !x0.WalkGoflat(e) {
+						return false
+					}
+					e.Leave(x0)
+				}
+			}
+			e.Leave(v.PtrMap)
+		}
+	}
+	if !e.Walk("Iface", "", v.Iface) {
+		return false
+	}
+	if !direct {
+		if !e.Walk("Next", "", v.Next) {
+			return false
+		}
+	} else {
+		if v.Next == nil {
+			if !e.Emit("Next", v.Next) {
+				return false
+			}
+		} else if e.Enter("Next", v.Next) {
+			if !v.Next.WalkGoflat(e) {
+				return false
+			}
+			e.Leave(v.Next)
+		}
+	}
+	if !e.Walk("Nested", "", v.Nested) {
+		return false
+	}
+	if !e.Walk("Embedded", "", v.Embedded) {
+		return false
+	}
+	if e.ExpandUnexported() && !e.Emit("private", v.private) {
+		return false
+	}
+	if e.ExpandUnexported() {
+		if parent == nil {
+			parent = v
+		}
+		if !e.WalkField("privatePtr", "", parent, 22) {
+			return false
+		}
+	}
+	if e.ExpandUnexported() {
+		if parent == nil {
+			parent = v
+		}
+		if !e.WalkField("privateMap", "", parent, 23) {
+			return false
+		}
+	}
+	return true
+}
+
+// GoflatGenerated marks Wrapper as having its own WalkGoflat method, see goflat.GeneratedMarker.
+func (Wrapper) GoflatGenerated(*Wrapper) {}
+
+// WalkGoflat visits the fields of Wrapper for goflat.Walk.
+func (v Wrapper) WalkGoflat(e goflat.Emitter) bool {
+	direct := e.Direct()
+	if !direct {
+		if !e.Walk("Item", "", v.Item) {
+			return false
+		}
+	} else {
+		if v.Item == nil {
+			if !e.Emit("Item", v.Item) {
+				return false
+			}
+		} else if e.Enter("Item", v.Item) {
+			if !v.Item.WalkGoflat(e) {
+				return false
+			}
+			e.Leave(v.Item)
+		}
+	}
+	if !e.Emit("Extra", v.Extra) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,207 @@
+package goflat
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Wildcard is a path element, which stands for any map key or slice element in the paths reported by WalkType.
+const Wildcard = "*"
+
+// TypeWalkFunc is a callback for WalkType.
+// The path slice is reused between the calls, so it must be copied to be retained.
+type TypeWalkFunc func(path []string, typ reflect.Type) bool
+
+// WalkType calls cb for every path, which Walk may report for a value of typ, with the type of the reported value.
+// Like WalkReflect, it keeps named scalar types, e.g. time.Duration is not reported as int64.
+// Map keys and slice elements are reported as Wildcard, elements addressed by a key field as "Items[ID=*]"
+// or "Items.*" depending on the KeyStyle. interface{} values are reported as is, as their dynamic types are unknown.
+// Collapsed slices are reported with the type CollapseMode gives them, sets with SetAsValue mode as []T.
+// The types, which are already being expanded, are not expanded again, so recursive types are walked once.
+// Nil pointers, which Walk reports regardless of the pointer policy, are not reported.
+// Fields of the kinds, which Walk doesn't report, like channels and functions, are skipped.
+func WalkType(typ reflect.Type, cb TypeWalkFunc, opts ...Option) {
+	if typ == nil {
+		return
+	}
+	newTypeWalker(cb, makeOptions(opts...)).visit(typ, nil)
+}
+
+type typeWalker struct {
+	cb TypeWalkFunc
+	o  *options
+	// recursion, if set, is called for the types, which are already being expanded.
+	// ref is the path of the value, where the type is expanded.
+	recursion func(path []string, typ reflect.Type, ref string) bool
+	// expanding are the container types on the current path with their paths.
+	expanding map[reflect.Type]string
+	// tag is the tag of the innermost struct field on the current path.
+	tag reflect.StructTag
+	// optional is the number of pointers, maps and slices on the current path.
+	optional int
+}
+
+func newTypeWalker(cb TypeWalkFunc, o *options) *typeWalker {
+	return &typeWalker{cb: cb, o: o, expanding: make(map[reflect.Type]string)}
+}
+
+func (tw *typeWalker) visit(typ reflect.Type, path []string) (cont bool) {
+	if tw.o.isLeaf != nil && tw.o.isLeaf(typ) {
+		return tw.cb(path, typ)
+	}
+	if !tw.o.expandBytes && isBytes(typ) {
+		return tw.cb(path, typ)
+	}
+	if scalarVisitors[typ.Kind()] != nil {
+		return tw.cb(path, typ)
+	}
+	switch typ.Kind() {
+	case reflect.Interface:
+		return tw.cb(path, typ)
+	case reflect.Pointer:
+		return tw.visitPointer(typ, path)
+	case reflect.Struct:
+		return tw.visitStruct(typ, path)
+	case reflect.Map:
+		return tw.visitMap(typ, path)
+	case reflect.Slice, reflect.Array:
+		return tw.visitSliceOrArray(typ, path, tw.o.sliceKeys[typ.Elem()])
+	}
+	return true
+}
+
+// enter marks a container type as being expanded. It returns false, if the type is already being expanded.
+// In such case cont is the result of the recursion callback.
+func (tw *typeWalker) enter(typ reflect.Type, path []string) (entered, cont bool) {
+	if ref, found := tw.expanding[typ]; found {
+		if tw.recursion != nil {
+			return false, tw.recursion(path, typ, ref)
+		}
+		return false, true
+	}
+	var ref string
+	if tw.recursion != nil {
+		ref = strings.Join(path, tw.o.delimeter)
+	}
+	tw.expanding[typ] = ref
+	return true, true
+}
+
+func (tw *typeWalker) visitPointer(typ reflect.Type, path []string) (cont bool) {
+	// the root pointer doesn't make the values optional, as it's the object itself.
+	if len(path) > 0 {
+		tw.optional++
+		defer func() {
+			tw.optional--
+		}()
+	}
+	elem := typ
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	switch tw.o.pointerFollowPolicy {
+	case PointerPolicyJustPointer:
+		return tw.cb(path, typ)
+	case PointerPolicyPrimitivePointer:
+		if isPrimitive(elem.Kind()) {
+			return tw.cb(path, typ)
+		}
+	case PointerPolicyBoth:
+		if !tw.cb(path, typ) {
+			return false
+		}
+	}
+	return tw.visit(elem, path)
+}
+
+func (tw *typeWalker) visitStruct(typ reflect.Type, path []string) (cont bool) {
+	if entered, cont := tw.enter(typ, path); !entered {
+		return cont
+	}
+	defer delete(tw.expanding, typ)
+	prevTag := tw.tag
+	defer func() {
+		tw.tag = prevTag
+	}()
+	for _, field := range tw.o.structFields(typ) {
+		tf := typ.Field(field.index)
+		fieldType, fieldPath := tf.Type, append(path, field.name)
+		tw.tag = tf.Tag
+		if field.key != "" {
+			cont = tw.visitSliceOrArray(fieldType, fieldPath, field.key)
+		} else {
+			cont = tw.visit(fieldType, fieldPath)
+		}
+		if !cont {
+			return false
+		}
+	}
+	return true
+}
+
+func (tw *typeWalker) visitMap(typ reflect.Type, path []string) (cont bool) {
+	if entered, cont := tw.enter(typ, path); !entered {
+		return cont
+	}
+	defer delete(tw.expanding, typ)
+	tw.optional++
+	defer func() {
+		tw.optional--
+	}()
+	if tw.o.setMode != SetNone && isSet(typ) {
+		if tw.o.setMode == SetAsValue {
+			return tw.cb(path, reflect.SliceOf(typ.Key()))
+		}
+		return tw.visit(typ.Key(), append(path, Wildcard))
+	}
+	if typ.Key().Kind() != reflect.String {
+		return true
+	}
+	return tw.visit(typ.Elem(), append(path, Wildcard))
+}
+
+func (tw *typeWalker) visitSliceOrArray(typ reflect.Type, path []string, key string) (cont bool) {
+	if entered, cont := tw.enter(typ, path); !entered {
+		return cont
+	}
+	defer delete(tw.expanding, typ)
+	if tw.o.shouldCollapse(typ, path) {
+		if tw.o.collapseMode == CollapseJoin {
+			return tw.cb(path, reflect.TypeOf(""))
+		}
+		return tw.cb(path, typ)
+	}
+	if typ.Kind() == reflect.Slice {
+		tw.optional++
+		defer func() {
+			tw.optional--
+		}()
+	}
+	if key != "" {
+		if segment := tw.o.keyedSegment(typ.Elem(), path, key); segment != "" {
+			return tw.visit(typ.Elem(), append(tw.o.keyedParent(path), segment))
+		}
+	}
+	return tw.visit(typ.Elem(), append(path, Wildcard))
+}
+
+// keyedSegment returns a path element, which stands for any element addressed by the key field.
+// It returns an empty string, if the elements can't have the field.
+func (o *options) keyedSegment(elem reflect.Type, path []string, key string) string {
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return ""
+	}
+	if _, found := elem.FieldByName(key); !found {
+		return ""
+	}
+	switch {
+	case o.keyStyle == KeyStyleSegment:
+		return Wildcard
+	case len(path) > 0:
+		return path[len(path)-1] + "[" + key + "=" + Wildcard + "]"
+	}
+	return "[" + key + "=" + Wildcard + "]"
+}
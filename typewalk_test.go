@@ -0,0 +1,142 @@
+package goflat
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typeWalkItem struct {
+	ID   int
+	Tags []string
+}
+
+type typeWalkNode struct {
+	Name     string
+	Children []*typeWalkNode
+	Parent   *typeWalkNode
+}
+
+type typeWalkStruct struct {
+	Int      int
+	Ptr      *float64
+	Duration time.Duration
+	Bytes    []byte
+	M        map[string]typeWalkItem
+	IntKeys  map[int]string
+	Items    []typeWalkItem `goflat:"items,key=ID"`
+	Array    [2]bool
+	Any      interface{}
+	Set      map[string]struct{}
+	Node     typeWalkNode
+	Skipped  int `goflat:"-"`
+	Chan     chan int
+	private  string
+}
+
+func walkTypePaths(typ reflect.Type, opts ...Option) map[string]reflect.Type {
+	res := make(map[string]reflect.Type)
+	WalkType(typ, func(path []string, typ reflect.Type) bool {
+		res[strings.Join(path, ".")] = typ
+		return true
+	}, opts...)
+	return res
+}
+
+func TestWalkType(t *testing.T) {
+	a := assert.New(t)
+	typ := reflect.TypeOf(typeWalkStruct{})
+	a.Equal(map[string]reflect.Type{
+		"Int":                reflect.TypeOf(0),
+		"Ptr":                reflect.TypeOf((*float64)(nil)),
+		"Duration":           reflect.TypeOf(time.Duration(0)),
+		"Bytes":              reflect.TypeOf([]byte(nil)),
+		"M.*.ID":             reflect.TypeOf(0),
+		"M.*.Tags.*":         reflect.TypeOf(""),
+		"items[ID=*].ID":     reflect.TypeOf(0),
+		"items[ID=*].Tags.*": reflect.TypeOf(""),
+		"Array.*":            reflect.TypeOf(false),
+		"Any":                reflect.TypeOf((*interface{})(nil)).Elem(),
+		"Node.Name":          reflect.TypeOf(""),
+	}, walkTypePaths(typ))
+
+	var kvs []KV
+	WalkType(reflect.TypeOf(&typeWalkNode{}), func(path []string, typ reflect.Type) bool {
+		kvs = append(kvs, KV{Path: strings.Join(path, "."), Value: typ})
+		return true
+	}, WithPointerFllowPolicy(PointerPolicyBoth))
+	nodePtr := reflect.TypeOf(&typeWalkNode{})
+	a.Equal([]KV{
+		{Path: "", Value: nodePtr},
+		{Path: "Name", Value: reflect.TypeOf("")},
+		{Path: "Children.*", Value: nodePtr},
+		{Path: "Parent", Value: nodePtr},
+	}, kvs)
+
+	paths := walkTypePaths(typ,
+		ExpandUnexported(true),
+		WithKeyStyle(KeyStyleSegment),
+		WithSetMode(SetAsValue),
+		CollapsePrimitiveSlices(CollapseJoin, ",", "items.*.Tags"),
+	)
+	a.Equal(reflect.TypeOf(""), paths["items.*.Tags"])
+	a.Equal(reflect.TypeOf(""), paths["M.*.Tags.*"])
+	a.Equal(reflect.TypeOf([]string(nil)), paths["Set"])
+	a.Equal(reflect.TypeOf(""), paths["private"])
+	a.Equal(reflect.TypeOf((*float64)(nil)), paths["Ptr"])
+	a.Equal(reflect.TypeOf(""), paths["Node.Name"])
+
+	var count int
+	WalkType(typ, func(path []string, typ reflect.Type) bool {
+		count++
+		return count < 3
+	})
+	a.Equal(3, count)
+	a.Equal(map[string]reflect.Type{"": reflect.TypeOf(0)}, walkTypePaths(reflect.TypeOf(0)))
+	a.Empty(walkTypePaths(nil))
+}
+
+var isIndex = regexp.MustCompile(`^[0-9]+$`)
+
+func TestWalkTypeMatchesWalk(t *testing.T) {
+	a := assert.New(t)
+	f := 1.5
+	obj := typeWalkStruct{
+		Int:   1,
+		Ptr:   &f,
+		Bytes: []byte("b"),
+		M:     map[string]typeWalkItem{"k": {ID: 1, Tags: []string{"a"}}},
+		Items: []typeWalkItem{{ID: 2, Tags: []string{"b"}}},
+		Any:   "any",
+		Set:   map[string]struct{}{"x": {}},
+		Node:  typeWalkNode{Name: "n"},
+	}
+	types := walkTypePaths(reflect.TypeOf(obj), WithSetMode(SetAsList))
+	Walk(obj, func(path []string, value interface{}) bool {
+		if val := reflect.ValueOf(value); val.Kind() == reflect.Pointer && val.IsNil() {
+			return true
+		}
+		generic := make([]string, len(path))
+		for i, elem := range path {
+			switch {
+			case elem == "k" || elem == "x" || isIndex.MatchString(elem):
+				generic[i] = Wildcard
+			case strings.HasPrefix(elem, "items["):
+				generic[i] = "items[ID=*]"
+			default:
+				generic[i] = elem
+			}
+		}
+		typ, found := types[strings.Join(generic, ".")]
+		if a.True(found, "%v", path) {
+			if typ.Kind() != reflect.Interface {
+				a.Equal(typ.Kind(), reflect.TypeOf(value).Kind(), "%v", path)
+			}
+		}
+		return true
+	}, WithSetMode(SetAsList))
+}